@@ -0,0 +1,532 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/YuukanOO/ease/pkg/generator"
+	"github.com/YuukanOO/ease/pkg/parser"
+)
+
+const (
+	exitOK       = 0
+	exitFailure  = 1 // Errors were reported
+	exitUsage    = 2 // The command line is invalid
+	exitOutdated = 3 // Generated files are not up to date in check mode
+
+	defaultCommand    = "generate"
+	defaultOutputDir  = "generated"
+	defaultParsers    = "api"
+	defaultGenerators = "gin"
+
+	formatText = "text"
+	formatJSON = "json"
+)
+
+var (
+	ErrUnknownCommand  = errors.New("unknown command")
+	ErrNoPackagesGiven = errors.New("missing packages names")
+	ErrUnknownFormat   = errors.New("unknown output format")
+)
+
+type (
+	command struct {
+		name    string
+		summary string
+		args    string   // Positional arguments in the usage line
+		formats []string // Output formats supported by the command, the first one is the default
+		watch   bool     // Whether the command supports the watch mode
+		run     func(*cli, []string) error
+	}
+
+	// Flags shared by every command parsing packages.
+	settings struct {
+		workDir    string
+		outputDir  string
+		parsers    listFlag
+		generators listFlag
+		buildTags  listFlag
+		configPath string
+		verbose    bool
+		quiet      bool
+		noCache    bool
+		format     string
+		watch      bool
+		exec       string
+		set        map[string]bool // Flags explicitly set on the command line
+	}
+
+	cli struct {
+		stdout io.Writer
+		stderr io.Writer
+	}
+
+	// Comma separated list of values, the flag may be repeated.
+	listFlag []string
+
+	// Wraps errors due to an invalid command line.
+	usageError struct {
+		err error
+	}
+)
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "generate", summary: "generate files from the given packages", args: "packages...", watch: true, run: (*cli).generate},
+		{name: "check", summary: "check generated files are up to date, printing a diff otherwise", args: "packages...", run: (*cli).check},
+		{name: "inspect", summary: "print what ease understood from the given packages", args: "packages...", formats: []string{formatText, formatJSON}, run: (*cli).inspect},
+		{name: "graph", summary: "print the dependency graph of the API", args: "packages...", formats: []string{formatText, formatDot, formatMermaid, formatJSON}, run: (*cli).graph},
+		{name: "directives", summary: "print the documentation of every known directive", run: (*cli).directives},
+		{name: "version", summary: "print the ease version", run: (*cli).version},
+		{name: "help", summary: "print this help or the one of the given command", args: "[command]", run: (*cli).help},
+	}
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+
+	return nil
+}
+
+// Runs the command line with the given arguments, without the program name, and returns
+// the process exit code.
+func (c *cli) run(args []string) int {
+	err := c.dispatch(args)
+
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+
+	var usage *usageError
+
+	switch {
+	case errors.As(err, &usage):
+		fmt.Fprintf(c.stderr, "ease: %v\nRun 'ease help' for usage.\n", err)
+		return exitUsage
+	case errors.Is(err, generator.ErrOutdated):
+		fmt.Fprintf(c.stderr, "ease: %v\n", err)
+		return exitOutdated
+	default:
+		fmt.Fprintf(c.stderr, "ease: %v\n", err)
+		return exitFailure
+	}
+}
+
+func (c *cli) dispatch(args []string) error {
+	if len(args) == 0 {
+		c.usage()
+		return &usageError{ErrNoPackagesGiven}
+	}
+
+	if cmd := findCommand(args[0]); cmd != nil {
+		return cmd.run(c, args[1:])
+	}
+
+	// Flags or packages without a command have always meant generate
+	if strings.HasPrefix(args[0], "-") || !isCommandName(args[0]) {
+		return findCommand(defaultCommand).run(c, args)
+	}
+
+	return &usageError{fmt.Errorf("%w %s", ErrUnknownCommand, args[0])}
+}
+
+func (c *cli) generate(args []string) error {
+	s, opts, err := c.parseSettings("generate", args)
+
+	if err != nil {
+		return err
+	}
+
+	if !s.watch {
+		return Run(opts...)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return Watch(ctx, append(opts, WithExec(s.exec))...)
+}
+
+func (c *cli) check(args []string) error {
+	_, opts, err := c.parseSettings("check", args)
+
+	if err != nil {
+		return err
+	}
+
+	return Run(append(opts, WithCheck())...)
+}
+
+func (c *cli) inspect(args []string) error {
+	_, opts, err := c.parseSettings("inspect", args)
+
+	if err != nil {
+		return err
+	}
+
+	return Inspect(opts...)
+}
+
+func (c *cli) graph(args []string) error {
+	_, opts, err := c.parseSettings("graph", args)
+
+	if err != nil {
+		return err
+	}
+
+	return Graph(opts...)
+}
+
+func (c *cli) directives(args []string) error {
+	var s settings
+
+	fs := c.flagSet("directives")
+	fs.StringVar(&s.configPath, "config", "", "configuration file to use instead of the ease.yaml or ease.toml at the module root")
+	fs.Var(&s.parsers, "parsers", "comma separated list of parsers whose directives should be printed (default "+defaultParsers+")")
+
+	if err := c.parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	s.set = make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { s.set[f.Name] = true })
+
+	workDir, err := filepath.Abs(".")
+
+	if err != nil {
+		return err
+	}
+
+	cfg, err := s.loadConfig(workDir)
+
+	if err != nil {
+		return err
+	}
+
+	parsers, _, err := buildExtensions(cfg, s.parserNames(cfg), nil, "")
+
+	if err != nil {
+		return &usageError{err}
+	}
+
+	prefix := cfg.DirectivePrefix
+
+	if prefix == "" {
+		prefix = parser.DefaultDirectivePrefix
+	}
+
+	return printDirectives(c.stdout, prefix, parser.New(parsers...).Directives())
+}
+
+func (c *cli) version(args []string) error {
+	if err := c.parseFlags(c.flagSet("version"), args); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(c.stdout, "ease %s\n", easeVersion())
+	return err
+}
+
+func (c *cli) help(args []string) error {
+	if len(args) == 0 {
+		c.usage()
+		return nil
+	}
+
+	cmd := findCommand(args[0])
+
+	if cmd == nil {
+		return &usageError{fmt.Errorf("%w %s", ErrUnknownCommand, args[0])}
+	}
+
+	fs := c.flagSet(cmd.name)
+
+	if cmd.args == "packages..." {
+		new(settings).register(fs, cmd)
+	}
+
+	fs.SetOutput(c.stdout)
+	fs.Usage()
+
+	return nil
+}
+
+// Parses flags and packages of commands working on packages and returns options to use.
+// Flags take precedence over the project configuration file.
+func (c *cli) parseSettings(name string, args []string) (*settings, []Option, error) {
+	var s settings
+
+	fs := c.flagSet(name)
+	s.register(fs, findCommand(name))
+
+	if err := c.parseFlags(fs, args); err != nil {
+		return nil, nil, err
+	}
+
+	if s.quiet && s.verbose {
+		return nil, nil, &usageError{errors.New("-q and -v are mutually exclusive")}
+	}
+
+	if s.exec != "" && !s.watch {
+		return nil, nil, &usageError{errors.New("-exec is only supported in watch mode")}
+	}
+
+	if err := findCommand(name).checkFormat(s.format); err != nil {
+		return nil, nil, &usageError{err}
+	}
+
+	workDir, err := filepath.Abs(s.workDir)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg, err := s.loadConfig(workDir)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.set = make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { s.set[f.Name] = true })
+
+	// Patterns of the configuration file are relative to it
+	packages, packagesDir := fs.Args(), workDir
+
+	if len(packages) == 0 {
+		packages, packagesDir = cfg.Packages, cfg.dir
+	}
+
+	if len(packages) == 0 {
+		return nil, nil, &usageError{ErrNoPackagesGiven}
+	}
+
+	parsers, outputs, err := buildExtensions(cfg, s.parserNames(cfg), s.generatorConfigs(cfg, workDir), workDir)
+
+	// Unlike unknown extensions, invalid template overrides do not come from the command line
+	if errors.Is(err, ErrInvalidConfig) {
+		return nil, nil, err
+	}
+
+	if err != nil {
+		return nil, nil, &usageError{err}
+	}
+
+	opts := []Option{
+		WithOutput(c.stdout, c.stderr),
+		WithPackages(packages...),
+		WithWorkDir(packagesDir),
+		WithManifest(cfg.manifestDir()),
+		WithBuildTags(s.buildTagsOr(cfg.Tags)...),
+		WithExclude(cfg.Exclude...),
+		WithDirectivePrefix(cfg.DirectivePrefix),
+		WithParsers(parsers...),
+		WithFormat(s.format),
+	}
+
+	for _, output := range outputs {
+		opts = append(opts, WithGenerators(output.dir, output.generators...))
+	}
+
+	if !s.noCache {
+		opts = append(opts, WithCache(defaultCacheDir(cfg.dir)))
+	}
+
+	switch {
+	case s.quiet:
+		opts = append(opts, WithVerbosity(VerbosityQuiet))
+	case s.verbose:
+		opts = append(opts, WithVerbosity(VerbosityVerbose))
+	}
+
+	return &s, opts, nil
+}
+
+func (s *settings) register(fs *flag.FlagSet, cmd *command) {
+	fs.StringVar(&s.workDir, "C", ".", "change to the given directory before doing anything")
+	fs.StringVar(&s.configPath, "config", "", "configuration file to use instead of the ease.yaml or ease.toml at the module root")
+	fs.StringVar(&s.outputDir, "o", defaultOutputDir, "output directory of every generator, relative to the working one")
+	fs.Var(&s.parsers, "parsers", "comma separated list of enabled parsers (default "+defaultParsers+")")
+	fs.Var(&s.generators, "generators", "comma separated list of enabled generators (default "+defaultGenerators+")")
+	fs.Var(&s.buildTags, "tags", "comma separated list of build tags to consider when loading packages")
+	fs.BoolVar(&s.verbose, "v", false, "report what ease is doing")
+	fs.BoolVar(&s.quiet, "q", false, "only report errors")
+	fs.BoolVar(&s.noCache, "no-cache", false, "do not use the cache of parsed packages")
+
+	if cmd.watch {
+		fs.BoolVar(&s.watch, "watch", false, "generate again every time a Go file of a parsed package changes")
+		fs.StringVar(&s.exec, "exec", "", "shell command restarted after each successful generation in watch mode, such as \"go run ./generated\"")
+	}
+
+	if len(cmd.formats) > 0 {
+		fs.StringVar(&s.format, "format", cmd.formats[0], "output format, one of "+strings.Join(cmd.formats, ", "))
+	}
+}
+
+// Checks the command supports the given output format, empty meaning the default one.
+func (cmd *command) checkFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+
+	for _, supported := range cmd.formats {
+		if supported == format {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w %s, available ones: %s", ErrUnknownFormat, format, strings.Join(cmd.formats, ", "))
+}
+
+// Reads the configuration file given by the flag or the one at the module root. Without
+// any, an empty configuration relative to the working directory is returned.
+func (s *settings) loadConfig(workDir string) (*projectConfig, error) {
+	var (
+		cfg *projectConfig
+		err error
+	)
+
+	if s.configPath != "" {
+		cfg, err = readConfig(resolvePath(workDir, s.configPath))
+	} else {
+		cfg, err = findConfig(workDir)
+	}
+
+	if err != nil || cfg != nil {
+		return cfg, err
+	}
+
+	return &projectConfig{dir: workDir}, nil
+}
+
+func (s *settings) parserNames(cfg *projectConfig) []string {
+	switch {
+	case s.set["parsers"]:
+		return s.parsers
+	case len(cfg.Parsers) > 0:
+		return cfg.Parsers
+	default:
+		return []string{defaultParsers}
+	}
+}
+
+// Retrieve generators to run with their output and templates directories resolved. The -o flag overrides
+// every output directory and -generators the configured generators, keeping their settings.
+func (s *settings) generatorConfigs(cfg *projectConfig, workDir string) []*generatorConfig {
+	var result []*generatorConfig
+
+	switch {
+	case s.set["generators"]:
+		for _, name := range s.generators {
+			generator := &generatorConfig{name: name}
+
+			if configured, found := cfg.Generators[name]; found {
+				*generator = *configured
+			}
+
+			result = append(result, generator)
+		}
+	case len(cfg.Generators) > 0:
+		for _, configured := range cfg.generators() {
+			generator := *configured
+			result = append(result, &generator)
+		}
+	default:
+		result = append(result, &generatorConfig{name: defaultGenerators})
+	}
+
+	for _, generator := range result {
+		if s.set["o"] {
+			generator.Output = resolvePath(workDir, s.outputDir)
+		} else {
+			generator.Output = generator.outputDir(cfg.dir)
+		}
+
+		if generator.Templates != "" {
+			generator.Templates = resolvePath(cfg.dir, generator.Templates)
+		}
+	}
+
+	return result
+}
+
+func (s *settings) buildTagsOr(tags []string) []string {
+	if s.set["tags"] {
+		return s.buildTags
+	}
+
+	return tags
+}
+
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	cmd := findCommand(name)
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ease %s [flags] %s\n\n%s.\n\nFlags:\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// Parses flags, invalid ones are usage errors.
+func (c *cli) parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+
+	return &usageError{err}
+}
+
+func (c *cli) usage() {
+	tw := tabwriter.NewWriter(c.stderr, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "ease generates the boring stuff from your Go packages.\n\nUsage: ease <command> [flags] [arguments]\n\nCommands:\n")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+
+	fmt.Fprintf(tw, "\nExit codes:\n  %d\tsuccess\n  %d\terrors were reported\n  %d\tinvalid command line\n  %d\tgenerated files are out of date (check)\n",
+		exitOK, exitFailure, exitUsage, exitOutdated)
+	fmt.Fprintln(tw, "\nRun 'ease help <command>' for more information about a command.")
+	tw.Flush()
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+
+	return nil
+}
+
+// Package patterns always contain a dot or a slash, so anything else looks like a mistyped command.
+func isCommandName(arg string) bool {
+	return !strings.ContainsAny(arg, "./")
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes a module made of the given files in a temporary directory and returns its path.
+func newModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/app\n\ngo 1.20\n"
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	return dir
+}
+
+// Runs the command line with the given arguments and returns its exit code and outputs.
+func runCLI(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+
+	c := &cli{stdout: &stdout, stderr: &stderr}
+	code := c.run(args)
+
+	return code, stdout.String(), stderr.String()
+}
+
+const todoSource = `package todo
+
+// ease:api path=/todos
+func List() []string { return nil }
+`
+
+func TestCLI(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		args     []string
+		code     int
+		expected string // Expected in the standard or error output
+	}{
+		{"no arguments", nil, exitUsage, "Usage: ease <command>"},
+		{"unknown command", []string{"generat"}, exitUsage, "unknown command generat"},
+		{"help of an unknown command", []string{"help", "nope"}, exitUsage, "unknown command nope"},
+		{"invalid flag", []string{"generate", "-nope", "./..."}, exitUsage, "flag provided but not defined: -nope"},
+		{"exclusive flags", []string{"generate", "-q", "-v", "./..."}, exitUsage, "-q and -v are mutually exclusive"},
+		{"exec without watch", []string{"generate", "-exec", "true", "./..."}, exitUsage, "-exec is only supported in watch mode"},
+		{"unknown format", []string{"inspect", "-format", "xml", "./..."}, exitUsage, "unknown output format xml"},
+		{"unknown generator", []string{"generate", "-generators", "nope", "./..."}, exitUsage, "nope"},
+		{"version", []string{"version"}, exitOK, "ease "},
+		{"help of a command", []string{"help", "check"}, exitOK, "Usage: ease check [flags] packages..."},
+		{"help flag", []string{"generate", "-h"}, exitOK, "Usage: ease generate"},
+	} {
+		t.Run("should handle "+test.name, func(t *testing.T) {
+			code, stdout, stderr := runCLI(test.args...)
+
+			if code != test.code || !strings.Contains(stdout+stderr, test.expected) {
+				t.Errorf("expected exit code %d with %q, got %d with:\n%s%s", test.code, test.expected, code, stdout, stderr)
+			}
+		})
+	}
+
+	t.Run("should generate when no command is given and check generated files", func(t *testing.T) {
+		dir := newModule(t, map[string]string{"todo/todo.go": todoSource})
+
+		if code, _, stderr := runCLI("-C", dir, "-q", "-no-cache", "./todo/..."); code != exitOK {
+			t.Fatalf("expected generate to succeed, got %d: %s", code, stderr)
+		}
+
+		server := filepath.Join(dir, defaultOutputDir, "server.go")
+
+		if _, err := os.Stat(server); err != nil {
+			t.Fatalf("expected the server to be generated: %v", err)
+		}
+
+		if code, _, stderr := runCLI("check", "-C", dir, "-q", "-no-cache", "./todo/..."); code != exitOK {
+			t.Fatalf("expected check to succeed, got %d: %s", code, stderr)
+		}
+
+		if err := os.WriteFile(server, []byte("package main\n"), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if code, stdout, _ := runCLI("check", "-C", dir, "-q", "-no-cache", "./todo/..."); code != exitOutdated || !strings.Contains(stdout, "server.go") {
+			t.Errorf("expected check to report the modified server, got %d: %s", code, stdout)
+		}
+	})
+
+	t.Run("should exit with a failure when errors are reported", func(t *testing.T) {
+		dir := newModule(t, map[string]string{"todo/todo.go": strings.Replace(todoSource, "path=", "method=FETCH path=", 1)})
+
+		if code, _, stderr := runCLI("generate", "-C", dir, "-no-cache", "./todo/..."); code != exitFailure || !strings.Contains(stderr, "invalid-method") {
+			t.Errorf("expected an invalid-method failure, got %d: %s", code, stderr)
+		}
+	})
+
+	t.Run("should not parse the configured output directory", func(t *testing.T) {
+		dir := newModule(t, map[string]string{
+			"todo/todo.go":  todoSource,
+			"gen/server.go": "package main\n\nfunc Broken( {\n",
+			"ease.yaml":     "packages: [./...]\ngenerators:\n  gin:\n    output: gen\n",
+		})
+
+		if code, _, stderr := runCLI("generate", "-C", dir, "-q", "-no-cache"); code != exitOK {
+			t.Errorf("expected a broken output to be overwritten, got %d: %s", code, stderr)
+		}
+	})
+
+	t.Run("should reject options of built-in generators", func(t *testing.T) {
+		dir := newModule(t, map[string]string{
+			"todo/todo.go": todoSource,
+			"ease.yaml":    "packages: [./todo/...]\ngenerators:\n  gin:\n    options:\n      router: chi\n",
+		})
+
+		if code, _, stderr := runCLI("generate", "-C", dir, "-q", "-no-cache"); code != exitFailure || !strings.Contains(stderr, "generators.gin.options: only plugins accept options") {
+			t.Errorf("expected options of gin to be rejected, got %d: %s", code, stderr)
+		}
+	})
+
+	t.Run("should mount packages under the configured prefix", func(t *testing.T) {
+		dir := newModule(t, map[string]string{
+			"todo/todo.go": todoSource,
+			"ease.yaml":    "packages: [./todo/...]\nmounts:\n  example.com/app/todo: /v2\n",
+		})
+
+		if code, _, stderr := runCLI("generate", "-C", dir, "-q", "-no-cache"); code != exitOK {
+			t.Fatalf("expected generate to succeed, got %d: %s", code, stderr)
+		}
+
+		server, err := os.ReadFile(filepath.Join(dir, defaultOutputDir, "server.go"))
+
+		if err != nil || !strings.Contains(string(server), `.Group("/v2")`) {
+			t.Errorf("expected endpoints to be mounted under /v2, got %v:\n%s", err, server)
+		}
+	})
+
+	t.Run("should remove files of the previous output directory", func(t *testing.T) {
+		dir := newModule(t, map[string]string{
+			"todo/todo.go": todoSource,
+			"ease.yaml":    "packages: [./todo/...]\ngenerators:\n  gin:\n    output: before\n",
+		})
+
+		if code, _, stderr := runCLI("generate", "-C", dir, "-q", "-no-cache"); code != exitOK {
+			t.Fatalf("expected generate to succeed, got %d: %s", code, stderr)
+		}
+
+		// Without any output, the default directory is used
+		if err := os.WriteFile(filepath.Join(dir, "ease.yaml"), []byte("packages: [./todo/...]\n"), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if code, _, stderr := runCLI("generate", "-C", filepath.Join(dir, "todo"), "-q", "-no-cache"); code != exitOK {
+			t.Fatalf("expected generate to succeed, got %d: %s", code, stderr)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "before")); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected the previous output directory to be removed, got %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, defaultOutputDir, "server.go")); err != nil {
+			t.Errorf("expected the server to be generated in the default directory: %v", err)
+		}
+	})
+
+	t.Run("should give precedence to flags over the configuration file", func(t *testing.T) {
+		dir := newModule(t, map[string]string{
+			"todo/todo.go": todoSource,
+			"ease.yaml":    "packages: [./todo/...]\ngenerators:\n  gin:\n    output: from-config\n",
+		})
+
+		if code, _, stderr := runCLI("generate", "-C", dir, "-q", "-no-cache"); code != exitOK {
+			t.Fatalf("expected generate to succeed, got %d: %s", code, stderr)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "from-config", "server.go")); err != nil {
+			t.Errorf("expected the configured output to be used: %v", err)
+		}
+
+		if code, _, stderr := runCLI("generate", "-C", dir, "-q", "-no-cache", "-o", "from-flag"); code != exitOK {
+			t.Fatalf("expected generate to succeed, got %d: %s", code, stderr)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "from-flag", "server.go")); err != nil {
+			t.Errorf("expected the output flag to override the configured one: %v", err)
+		}
+	})
+}
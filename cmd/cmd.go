@@ -1,40 +1,207 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/YuukanOO/ease/pkg/diagnostic"
 	"github.com/YuukanOO/ease/pkg/generator"
 	"github.com/YuukanOO/ease/pkg/parser"
 )
 
+const (
+	VerbosityQuiet   Verbosity = iota - 1 // Only report errors
+	VerbosityNormal                       // Report every diagnostic
+	VerbosityVerbose                      // Report every diagnostic and what ease is doing
+)
+
 type (
 	options struct {
-		packages   []string
-		outputDir  string
-		parsers    []parser.Extension
-		generators []generator.Extension
+		packages  []string
+		cacheDir  string
+		workDir   string
+		buildTags []string
+		exclude   []string
+		prefix    string
+		outputs   []*outputGroup
+		manifest  string // Directory of the manifest shared by output directories
+		check     bool
+		exec      string // Command restarted after each successful generation in watch mode
+		format    string
+		verbosity Verbosity
+		stdout    io.Writer
+		stderr    io.Writer
+		parsers   []parser.Extension
 	}
 
-	Option func(*options)
+	Option    func(*options)
+	Verbosity int
 )
 
-// Run ease with the following options.
+// Run ease with the following options. Diagnostics are reported on the standard error
+// output and an error is returned if at least one of them is an error. In check mode,
+// differences with generated files are printed on the standard output and a
+// *generator.OutdatedError is returned.
 func Run(opts ...Option) error {
-	var o options
+	_, err := newOptions(opts).run()
+	return err
+}
+
+// Parses and generates once, the parse result is returned even if generation failed.
+func (o *options) run() (parser.Result, error) {
+	parseResult, err := o.parse()
+
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := parseResult.Diagnostics()
+
+	var outdated *generator.OutdatedError
+
+	// Do not generate anything based on an invalid parse result
+	if !diagnostics.HasErrors() {
+		outdated = o.generate(parseResult)
+	}
+
+	if err := o.report(diagnostics); err != nil {
+		return parseResult, err
+	}
+
+	if outdated != nil {
+		printChanges(o.stdout, outdated.Changes)
+		return parseResult, outdated
+	}
+
+	return parseResult, nil
+}
+
+// Runs generators of every output directory. Errors are reported as diagnostics, except
+// for differences found in check mode which are merged and returned.
+func (o *options) generate(result parser.Result) *generator.OutdatedError {
+	var merged *generator.OutdatedError
+
+	for _, output := range o.outputs {
+		var outdated *generator.OutdatedError
+
+		start := time.Now()
+		err := generator.NewWithConfig(generator.Config{
+			Dir:         output.dir,
+			ManifestDir: o.manifest,
+			Check:       o.check,
+		}, output.generators...).Generate(result)
+
+		if !errors.As(err, &outdated) {
+			result.Diagnostics().Report(err)
+		} else if merged == nil {
+			merged = outdated
+		} else {
+			merged.Changes = append(merged.Changes, outdated.Changes...)
+		}
+
+		o.logf("generated %s in %s", output.dir, time.Since(start))
+	}
+
+	return merged
+}
+
+func printChanges(w io.Writer, changes []*generator.Change) {
+	for _, change := range changes {
+		fmt.Fprint(w, change.Diff)
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
 
 	for _, opt := range opts {
-		opt(&o)
+		opt(o)
+	}
+
+	return o
+}
+
+// Parse configured packages with configured parsers.
+func (o *options) parse() (parser.Result, error) {
+	var cache *parser.Cache
+
+	if o.cacheDir != "" {
+		cache = parser.NewCache(o.cacheDir, cacheKey())
+	}
+
+	start := time.Now()
+	result, err := parser.NewWithConfig(parser.Config{
+		Cache:           cache,
+		Dir:             o.workDir,
+		BuildTags:       o.buildTags,
+		DirectivePrefix: o.prefix,
+		Exclude:         o.exclude,
+		Outputs:         o.outputDirs(),
+	}, o.parsers...).Parse(o.packages...)
+
+	if err != nil {
+		return nil, err
 	}
 
-	parseResult, err := parser.
-		New(o.parsers...).
-		Parse(o.packages...)
+	o.logf("parsed %d package(s) in %s", len(result.Packages()), time.Since(start))
+
+	return result, nil
+}
+
+// Directories every generator writes to.
+func (o *options) outputDirs() []string {
+	dirs := make([]string, len(o.outputs))
+
+	for i, output := range o.outputs {
+		dirs[i] = output.dir
+	}
+
+	return dirs
+}
+
+// Root of the module of parsed packages, the working directory if there is none.
+func (o *options) moduleRoot() string {
+	dir, err := filepath.Abs(o.workDir)
 
 	if err != nil {
-		return err
+		return o.workDir
+	}
+
+	if root := moduleRoot(dir); root != "" {
+		return root
 	}
 
-	return generator.
-		New(o.outputDir, o.generators...).
-		Generate(parseResult)
+	return dir
+}
+
+// Writes diagnostics allowed by the verbosity on the error output and returns an error
+// if at least one of them is an error.
+func (o *options) report(diagnostics *diagnostic.Diagnostics) error {
+	for _, d := range diagnostics.Items() {
+		if o.verbosity == VerbosityQuiet && d.Severity != diagnostic.SeverityError {
+			continue
+		}
+
+		fmt.Fprintln(o.stderr, d)
+	}
+
+	return diagnostics.Err()
+}
+
+// Logs what ease is doing when verbose.
+func (o *options) logf(format string, args ...any) {
+	if o.verbosity < VerbosityVerbose {
+		return
+	}
+
+	fmt.Fprintf(o.stderr, format+"\n", args...)
 }
 
 // Add packages to be parsed.
@@ -44,6 +211,90 @@ func WithPackages(packages ...string) Option {
 	}
 }
 
+// WithCache stores parsed declarations in the given directory so unchanged packages are
+// not parsed again. An empty directory disables the cache.
+func WithCache(dir string) Option {
+	return func(o *options) {
+		o.cacheDir = dir
+	}
+}
+
+// WithManifest stores the manifest of generated files in the given directory, such as the
+// module root, instead of each output directory so files of a generator are removed from
+// its previous output directory when it changes.
+func WithManifest(dir string) Option {
+	return func(o *options) {
+		o.manifest = dir
+	}
+}
+
+// WithWorkDir resolves packages patterns in the given directory instead of the current one.
+func WithWorkDir(dir string) Option {
+	return func(o *options) {
+		o.workDir = dir
+	}
+}
+
+// WithBuildTags considers the given build tags when loading packages.
+func WithBuildTags(tags ...string) Option {
+	return func(o *options) {
+		o.buildTags = tags
+	}
+}
+
+// WithExclude ignores packages matching the given patterns, such as example.com/mod/internal/...
+func WithExclude(patterns ...string) Option {
+	return func(o *options) {
+		o.exclude = patterns
+	}
+}
+
+// WithDirectivePrefix looks for directives starting with the given prefix instead of ease.
+func WithDirectivePrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithCheck does not write generated files but compares them with the ones on disk.
+func WithCheck() Option {
+	return func(o *options) {
+		o.check = true
+	}
+}
+
+// WithFormat sets the format of what is written on the standard output by commands
+// supporting multiple ones, such as Inspect. Empty means the default one.
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithExec restarts the given shell command, such as "go run ./generated", after each
+// successful generation in watch mode.
+func WithExec(command string) Option {
+	return func(o *options) {
+		o.exec = command
+	}
+}
+
+// WithVerbosity sets what should be reported on the error output.
+func WithVerbosity(verbosity Verbosity) Option {
+	return func(o *options) {
+		o.verbosity = verbosity
+	}
+}
+
+// WithOutput sets where results and diagnostics are written, defaults to the standard
+// output and error.
+func WithOutput(stdout, stderr io.Writer) Option {
+	return func(o *options) {
+		o.stdout = stdout
+		o.stderr = stderr
+	}
+}
+
 // WithParsers set the parsers to be used.
 func WithParsers(parsers ...parser.Extension) Option {
 	return func(o *options) {
@@ -51,10 +302,10 @@ func WithParsers(parsers ...parser.Extension) Option {
 	}
 }
 
-// WithGenerators set the generators to be used and the output directory.
+// WithGenerators adds generators to be used with their output directory. It may be used
+// multiple times to generate files in different directories.
 func WithGenerators(outputDir string, generators ...generator.Extension) Option {
 	return func(o *options) {
-		o.outputDir = outputDir
-		o.generators = generators
+		o.outputs = addToGroup(o.outputs, outputDir, generators...)
 	}
 }
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/YuukanOO/ease/pkg/generator/plugin"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidConfig   = errors.New("invalid configuration")
+	ErrAmbiguousConfig = errors.New("multiple configuration files found")
+)
+
+// Configuration files looked up at the module root, in this order.
+var configFilenames = []string{"ease.yaml", "ease.yml", "ease.toml"}
+
+type (
+	// Project configuration, usually read from an ease.yaml or ease.toml file at the root
+	// of the module so the generation is reproducible.
+	projectConfig struct {
+		Packages        []string                    `yaml:"packages" toml:"packages"`                 // Patterns of packages to parse
+		Exclude         []string                    `yaml:"exclude" toml:"exclude"`                   // Patterns of packages to ignore
+		Tags            []string                    `yaml:"tags" toml:"tags"`                         // Build tags used to load packages
+		DirectivePrefix string                      `yaml:"directive_prefix" toml:"directive_prefix"` // Prefix of directives, ease by default
+		Parsers         []string                    `yaml:"parsers" toml:"parsers"`                   // Enabled parsers
+		Generators      map[string]*generatorConfig `yaml:"generators" toml:"generators"`             // Enabled generators by name
+		Mounts          map[string]string           `yaml:"mounts" toml:"mounts"`                     // Path prefixes of endpoints by package path
+
+		path string // Path of the file it was read from, empty if none
+		dir  string // Relative paths are resolved from this directory
+	}
+
+	generatorConfig struct {
+		Output    string            `yaml:"output" toml:"output"`       // Output directory
+		Package   string            `yaml:"package" toml:"package"`     // Name of the generated package
+		Templates string            `yaml:"templates" toml:"templates"` // Directory of template overrides
+		Options   map[string]string `yaml:"options" toml:"options"`     // Options given as is to plugins
+
+		name string
+	}
+)
+
+// Looks for a configuration file at the root of the module containing the given directory.
+// Returns nil without any error if there is none.
+func findConfig(dir string) (*projectConfig, error) {
+	root := moduleRoot(dir)
+
+	if root == "" {
+		return nil, nil
+	}
+
+	var found []string
+
+	for _, name := range configFilenames {
+		path := filepath.Join(root, name)
+
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+		return readConfig(found[0])
+	default:
+		return nil, fmt.Errorf("%w in %s, keep only one of them", ErrAmbiguousConfig, root)
+	}
+}
+
+// Returns the closest directory containing a go.mod file, or an empty string if none.
+func moduleRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+
+		parent := filepath.Dir(dir)
+
+		if parent == dir {
+			return ""
+		}
+
+		dir = parent
+	}
+}
+
+// Reads and validates the configuration file at the given path, its format is deduced
+// from its extension.
+func readConfig(path string) (*projectConfig, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	path, err = filepath.Abs(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &projectConfig{
+		path: path,
+		dir:  filepath.Dir(path),
+	}
+
+	switch filepath.Ext(path) {
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(cfg)
+
+		var strict *toml.StrictMissingError
+
+		if errors.As(err, &strict) {
+			err = errors.New(strict.String())
+		}
+	default:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+
+		// An empty file is a valid, empty, configuration
+		if err = decoder.Decode(cfg); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", path, ErrInvalidConfig, err)
+	}
+
+	for name, generator := range cfg.Generators {
+		// A generator without any setting is decoded as nil
+		if generator == nil {
+			generator = &generatorConfig{}
+			cfg.Generators[name] = generator
+		}
+
+		generator.name = name
+	}
+
+	if err = cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Checks the configuration is valid, reporting every problem at once.
+func (c *projectConfig) validate() error {
+	var problems []string
+
+	invalid := func(field, format string, args ...any) {
+		problems = append(problems, field+": "+fmt.Sprintf(format, args...))
+	}
+
+	if c.DirectivePrefix != "" && !token.IsIdentifier(c.DirectivePrefix) {
+		invalid("directive_prefix", "%q is not a valid identifier", c.DirectivePrefix)
+	}
+
+	for i, name := range c.Parsers {
+		if _, found := parserFactories[name]; !found {
+			invalid(fmt.Sprintf("parsers[%d]", i), "unknown parser %s, available ones: %s", name, available(parserFactories))
+		}
+	}
+
+	for _, pkg := range sortedKeys(c.Mounts) {
+		if prefix := c.Mounts[pkg]; !strings.HasPrefix(prefix, "/") {
+			invalid("mounts."+pkg, "%q must start with a /", prefix)
+		}
+	}
+
+	for _, generator := range c.generators() {
+		field := "generators." + generator.name
+		_, found := generatorFactories[generator.name]
+
+		if !found && !isPlugin(generator.name) {
+			invalid(field, "unknown generator, available ones: %s (or install %s%s in your PATH)",
+				available(generatorFactories), plugin.ExecutablePrefix, generator.name)
+			continue
+		}
+
+		if generator.Output != "" && filepath.IsAbs(generator.Output) {
+			invalid(field+".output", "%s must be relative to the configuration file", generator.Output)
+		}
+
+		if generator.Templates != "" && !found {
+			invalid(field+".templates", "templates can only be overridden for built-in generators")
+		} else if generator.Templates != "" && filepath.IsAbs(generator.Templates) {
+			invalid(field+".templates", "%s must be relative to the configuration file", generator.Templates)
+		}
+
+		if generator.Package != "" && !token.IsIdentifier(generator.Package) {
+			invalid(field+".package", "%q is not a valid package name", generator.Package)
+		}
+
+		// Options are only known by plugins
+		if found && len(generator.Options) > 0 {
+			invalid(field+".options", "only plugins accept options")
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w:\n  %s", ErrInvalidConfig, strings.Join(problems, "\n  "))
+}
+
+// Retrieve configured generators sorted by name so they always run in the same order.
+func (c *projectConfig) generators() []*generatorConfig {
+	result := make([]*generatorConfig, 0, len(c.Generators))
+
+	for _, name := range sortedKeys(c.Generators) {
+		result = append(result, c.Generators[name])
+	}
+
+	return result
+}
+
+// Retrieve the directory of the manifest of generated files, being the root of the module
+// so it does not depend on the output directories nor the working one.
+func (c *projectConfig) manifestDir() string {
+	if root := moduleRoot(c.dir); root != "" {
+		return root
+	}
+
+	return c.dir
+}
+
+// Retrieve the output directory of the generator, relative ones are resolved from the
+// given directory.
+func (g *generatorConfig) outputDir(dir string) string {
+	if g.Output == "" {
+		return filepath.Join(dir, defaultOutputDir)
+	}
+
+	return resolvePath(dir, g.Output)
+}
+
+// Resolves the given path from the given directory if it is relative.
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
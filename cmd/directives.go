@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/YuukanOO/ease/pkg/parser"
+)
+
+// Writes a human readable documentation of the given directives.
+func printDirectives(w io.Writer, prefix string, directives []*parser.DirectiveSchema) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	for _, d := range directives {
+		fmt.Fprintf(tw, "%s:%s (%s)\n", prefix, d.Name, d.Targets)
+		fmt.Fprintf(tw, "  %s\n", d.Doc)
+
+		if d.Args != "" {
+			fmt.Fprintf(tw, "    <args>\tlist\t%s\n", d.Args)
+		}
+
+		for _, p := range d.Params {
+			fmt.Fprintf(tw, "    %s\t%s\t%s\n", p.Name, p.Type, p.Doc)
+		}
+
+		fmt.Fprintln(tw)
+	}
+
+	return tw.Flush()
+}
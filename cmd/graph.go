@@ -0,0 +1,367 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/YuukanOO/ease/pkg/collection"
+	"github.com/YuukanOO/ease/pkg/parser"
+	"github.com/YuukanOO/ease/pkg/parser/api"
+)
+
+// Version of the JSON graph format, bumped on breaking changes only.
+const graphFormatVersion = 1
+
+const (
+	formatDot     = "dot"
+	formatMermaid = "mermaid"
+
+	nodeEndpoint    = "endpoint"
+	nodeMiddleware  = "middleware"
+	nodeVerifier    = "verifier"
+	nodeHandler     = "handler"
+	nodeType        = "type"
+	nodeConstructor = "constructor"
+
+	statusUnresolved = "unresolved" // No constructor provides the type or it can not be provided at all
+	statusAmbiguous  = "ambiguous"  // Multiple constructors provide the type, the first one is used
+
+	// Every dependency is built once when the generated server starts.
+	scopeSingleton = "singleton"
+)
+
+type (
+	// Dependency graph of an API, from endpoints to the constructors needed to serve them.
+	graph struct {
+		Version int                         `json:"version"`
+		Nodes   *collection.Set[*graphNode] `json:"nodes"`
+		Edges   []*graphEdge                `json:"edges"`
+	}
+
+	graphNode struct {
+		ID        string   `json:"id"`
+		Kind      string   `json:"kind"`
+		Label     string   `json:"label"`
+		Scope     string   `json:"scope,omitempty"`
+		Status    string   `json:"status,omitempty"`
+		Consumers []string `json:"consumers,omitempty"` // Ids of endpoints, middlewares and verifiers needing a root type
+	}
+
+	graphEdge struct {
+		From  string `json:"from"`
+		To    string `json:"to"`
+		Label string `json:"label,omitempty"` // Name of the param needing the type
+	}
+)
+
+// Graph parses configured packages and writes the dependency graph of the API on the
+// standard output, in the configured format.
+func Graph(opts ...Option) error {
+	o := newOptions(opts)
+	result, err := o.parse()
+
+	if err != nil {
+		return err
+	}
+
+	if err = o.report(result.Diagnostics()); err != nil {
+		return err
+	}
+
+	schema := apiSchema(o.parsers)
+
+	if schema == nil {
+		return fmt.Errorf("%w: graph needs api", ErrMissingParser)
+	}
+
+	g := buildGraph(result, schema)
+
+	switch o.format {
+	case formatDot:
+		return g.writeDot(o.stdout)
+	case formatMermaid:
+		return g.writeMermaid(o.stdout)
+	case formatJSON:
+		return writeJSON(o.stdout, g)
+	default:
+		return g.writeText(o.stdout)
+	}
+}
+
+// Builds the graph of the given API. Unlike Funcs.Resolve, it does not stop on types
+// which can not be resolved but flags them so they can be highlighted.
+func buildGraph(result parser.Result, schema *api.API) *graph {
+	var (
+		g         = &graph{Version: graphFormatVersion, Nodes: collection.NewSet[*graphNode]()}
+		fns       = result.Funcs()
+		providers = make(map[string]bool) // Constructors already visited
+	)
+
+	var require func(*parser.Var) string
+
+	// Adds the type needed by the given var and the constructors providing it
+	require = func(v *parser.Var) string {
+		if v.IsUnsupported() {
+			node := g.node(nodeType, v.TypeExpr().String())
+			node.Status = statusUnresolved
+			return node.ID
+		}
+
+		typ := v.TypeExpr()
+		node := g.node(nodeType, typ.Instance())
+
+		// Already visited, which also protects against cycles
+		if node.Scope != "" {
+			return node.ID
+		}
+
+		node.Scope = scopeSingleton
+		deps := fns.Providers(typ)
+
+		switch len(deps) {
+		case 0:
+			node.Status = statusUnresolved
+		case 1:
+		default:
+			node.Status = statusAmbiguous
+		}
+
+		for _, dep := range deps {
+			constructor := g.node(nodeConstructor, dependencyLabel(dep))
+			constructor.Scope = scopeSingleton
+			g.edge(node.ID, constructor.ID, "")
+
+			if providers[constructor.ID] {
+				continue
+			}
+
+			providers[constructor.ID] = true
+
+			for _, param := range dep.Params() {
+				g.edge(constructor.ID, require(param), param.Name())
+			}
+		}
+
+		return node.ID
+	}
+
+	// Handlers are needed by endpoints, middlewares and verifiers, their receiver and
+	// other dependencies are the roots of the graph
+	handler := func(from *graphNode, fn *parser.Func, deps ...*parser.Var) {
+		node := g.node(nodeHandler, fn.ID())
+		g.edge(from.ID, node.ID, "")
+
+		if recv := fn.Recv(); recv != nil {
+			deps = append([]*parser.Var{recv}, deps...)
+		}
+
+		for _, dep := range deps {
+			root := require(dep)
+			g.edge(node.ID, root, dep.Name())
+
+			if consumer, found := g.Nodes.Get(root); found && !contains(consumer.Consumers, from.ID) {
+				consumer.Consumers = append(consumer.Consumers, from.ID)
+			}
+		}
+	}
+
+	for _, endpoint := range schema.Endpoints() {
+		handler(g.node(nodeEndpoint, endpoint.String()), endpoint.Handler())
+	}
+
+	for _, middleware := range schema.Middlewares() {
+		handler(g.node(nodeMiddleware, middleware.Name()), middleware.Handler(), middleware.Dependencies()...)
+	}
+
+	for _, verifier := range schema.Verifiers() {
+		handler(g.node(nodeVerifier, verifier.Scheme()), verifier.Handler())
+	}
+
+	return g
+}
+
+// Label of a constructor, type arguments of generic ones are included since each
+// instantiation is a distinct constructor.
+func dependencyLabel(dep *parser.Dependency) string {
+	if len(dep.TypeArgs()) == 0 {
+		return dep.String()
+	}
+
+	args := make([]string, len(dep.TypeArgs()))
+
+	for i, arg := range dep.TypeArgs() {
+		args[i] = arg.String()
+	}
+
+	return dep.String() + "[" + strings.Join(args, ", ") + "]"
+}
+
+// Adds a node if it does not exist yet and returns it.
+func (g *graph) node(kind, label string) *graphNode {
+	id := kind + ":" + label
+
+	return g.Nodes.SetFunc(id, func() *graphNode {
+		return &graphNode{ID: id, Kind: kind, Label: label}
+	})
+}
+
+func (g *graph) edge(from, to, label string) {
+	for _, e := range g.Edges {
+		if e.From == from && e.To == to && e.Label == label {
+			return
+		}
+	}
+
+	g.Edges = append(g.Edges, &graphEdge{From: from, To: to, Label: label})
+}
+
+func (g *graph) writeText(w io.Writer) error {
+	label := func(id string) string {
+		node, _ := g.Nodes.Get(id)
+
+		if node.Status != "" {
+			return fmt.Sprintf("%s (%s)", node.Label, node.Status)
+		}
+
+		return node.Label
+	}
+
+	for _, e := range g.Edges {
+		line := label(e.From) + " -> " + label(e.To)
+
+		if e.Label != "" {
+			line += " [" + e.Label + "]"
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Graphviz attributes of each node kind and status.
+var dotStyles = map[string]string{
+	nodeEndpoint:     `shape=box, style="rounded,filled", fillcolor="#dbeafe"`,
+	nodeMiddleware:   `shape=box, style="rounded,filled", fillcolor="#ede9fe"`,
+	nodeVerifier:     `shape=box, style="rounded,filled", fillcolor="#fef9c3"`,
+	nodeHandler:      `shape=ellipse`,
+	nodeConstructor:  `shape=ellipse, style=dashed`,
+	nodeType:         `shape=box`,
+	statusUnresolved: `color="#dc2626", fontcolor="#dc2626", penwidth=2`,
+	statusAmbiguous:  `color="#ea580c", fontcolor="#ea580c", penwidth=2`,
+}
+
+func (g *graph) writeDot(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("digraph ease {\n\trankdir=LR;\n")
+
+	for _, node := range g.Nodes.Items() {
+		attrs := dotStyles[node.Kind]
+
+		if node.Status != "" {
+			attrs += ", " + dotStyles[node.Status]
+		}
+
+		fmt.Fprintf(&b, "\t%q [label=%q, %s];\n", node.ID, nodeLabel(node), attrs)
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "\t%q -> %q", e.From, e.To)
+
+		if e.Label != "" {
+			fmt.Fprintf(&b, " [label=%q]", e.Label)
+		}
+
+		b.WriteString(";\n")
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Mermaid shapes of each node kind and classes of each status.
+var (
+	mermaidShapes = map[string][2]string{
+		nodeEndpoint:    {"([", "])"},
+		nodeMiddleware:  {"([", "])"},
+		nodeVerifier:    {"([", "])"},
+		nodeHandler:     {"(", ")"},
+		nodeConstructor: {"(", ")"},
+		nodeType:        {"[", "]"},
+	}
+	mermaidClasses = map[string]string{
+		statusUnresolved: "fill:#fee2e2,stroke:#dc2626,color:#dc2626",
+		statusAmbiguous:  "fill:#ffedd5,stroke:#ea580c,color:#ea580c",
+	}
+)
+
+func (g *graph) writeMermaid(w io.Writer) error {
+	var (
+		b   strings.Builder
+		ids = make(map[string]string) // Mermaid ids by node id
+	)
+
+	b.WriteString("flowchart LR\n")
+
+	for i, node := range g.Nodes.Items() {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+		shape := mermaidShapes[node.Kind]
+		fmt.Fprintf(&b, "\t%s%s\"%s\"%s\n", ids[node.ID], shape[0], mermaidEscape(nodeLabel(node)), shape[1])
+	}
+
+	for _, e := range g.Edges {
+		if e.Label != "" {
+			fmt.Fprintf(&b, "\t%s -->|\"%s\"| %s\n", ids[e.From], mermaidEscape(e.Label), ids[e.To])
+		} else {
+			fmt.Fprintf(&b, "\t%s --> %s\n", ids[e.From], ids[e.To])
+		}
+	}
+
+	for _, status := range []string{statusUnresolved, statusAmbiguous} {
+		var flagged []string
+
+		for _, node := range g.Nodes.Items() {
+			if node.Status == status {
+				flagged = append(flagged, ids[node.ID])
+			}
+		}
+
+		if len(flagged) > 0 {
+			fmt.Fprintf(&b, "\tclassDef %s %s\n\tclass %s %s\n", status, mermaidClasses[status], strings.Join(flagged, ","), status)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Label of a node in graphical formats, its kind and status are made visible.
+func nodeLabel(node *graphNode) string {
+	label := node.Kind + "\n" + node.Label
+
+	if node.Status != "" {
+		label += "\n(" + node.Status + ")"
+	}
+
+	return label
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", "<br/>").Replace(s)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Service needs a store, an ambiguous logger and a clock nothing provides.
+const graphSource = `package todo
+
+type (
+	Store   struct{}
+	Logger  struct{}
+	Clock   interface{ Now() int }
+	Service struct{}
+)
+
+func NewStore() *Store { return &Store{} }
+
+func NewLogger() *Logger      { return &Logger{} }
+func NewDebugLogger() *Logger { return &Logger{} }
+
+func NewService(store *Store, logger *Logger, clock Clock) *Service { return &Service{} }
+
+// ease:api path=/todos
+func (s *Service) List() []string { return nil }
+`
+
+func TestGraph(t *testing.T) {
+	dir := newModule(t, map[string]string{"todo/todo.go": graphSource})
+
+	graphOf := func(t *testing.T, format string) string {
+		code, stdout, stderr := runCLI("graph", "-C", dir, "-q", "-no-cache", "-format", format, "./todo/...")
+
+		if code != exitOK {
+			t.Fatalf("expected the graph to be printed, got %d: %s", code, stderr)
+		}
+
+		return stdout
+	}
+
+	t.Run("should output nodes with their status and edges as JSON", func(t *testing.T) {
+		var g struct {
+			Version int          `json:"version"`
+			Nodes   []*graphNode `json:"nodes"`
+			Edges   []*graphEdge `json:"edges"`
+		}
+
+		if err := json.Unmarshal([]byte(graphOf(t, formatJSON)), &g); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if g.Version != graphFormatVersion || len(g.Nodes) != 10 || len(g.Edges) != 9 {
+			t.Fatalf("expected version %d with 10 nodes and 9 edges, got %d with %d nodes and %d edges",
+				graphFormatVersion, g.Version, len(g.Nodes), len(g.Edges))
+		}
+
+		nodes := make(map[string]*graphNode, len(g.Nodes))
+
+		for _, node := range g.Nodes {
+			nodes[node.ID] = node
+		}
+
+		for id, expected := range map[string]string{
+			"endpoint:GET /todos":                             "endpoint|||",
+			"type:example.com/app/todo.Service":               "type|singleton||endpoint:GET /todos",
+			"type:example.com/app/todo.Logger":                "type|singleton|ambiguous|",
+			"type:example.com/app/todo.Clock":                 "type|singleton|unresolved|",
+			"constructor:example.com/app/todo.NewDebugLogger": "constructor|singleton||",
+		} {
+			node, found := nodes[id]
+
+			if !found {
+				t.Errorf("expected a %s node", id)
+				continue
+			}
+
+			if got := strings.Join([]string{node.Kind, node.Scope, node.Status, strings.Join(node.Consumers, ",")}, "|"); got != expected {
+				t.Errorf("expected %s to be %q, got %q", id, expected, got)
+			}
+		}
+
+		edges := make(map[string]bool, len(g.Edges))
+
+		for _, e := range g.Edges {
+			edges[e.From+" -> "+e.To+" "+e.Label] = true
+		}
+
+		for _, expected := range []string{
+			"endpoint:GET /todos -> handler:example.com/app/todo.(*Service).List ",
+			"handler:example.com/app/todo.(*Service).List -> type:example.com/app/todo.Service s",
+			"constructor:example.com/app/todo.NewService -> type:example.com/app/todo.Clock clock",
+			"type:example.com/app/todo.Logger -> constructor:example.com/app/todo.NewLogger ",
+			"type:example.com/app/todo.Logger -> constructor:example.com/app/todo.NewDebugLogger ",
+		} {
+			if !edges[expected] {
+				t.Errorf("expected the edge %s", expected)
+			}
+		}
+	})
+
+	t.Run("should output a styled DOT digraph", func(t *testing.T) {
+		dot := graphOf(t, formatDot)
+		lines := strings.Split(strings.TrimSuffix(dot, "\n"), "\n")
+
+		if len(lines) != 22 || lines[0] != "digraph ease {" || lines[1] != "\trankdir=LR;" || lines[len(lines)-1] != "}" {
+			t.Fatalf("expected a digraph with 10 nodes and 9 edges, got:\n%s", dot)
+		}
+
+		for _, expected := range []string{
+			`	"endpoint:GET /todos" [label="endpoint\nGET /todos", shape=box, style="rounded,filled", fillcolor="#dbeafe"];`,
+			`	"type:example.com/app/todo.Clock" [label="type\nexample.com/app/todo.Clock\n(unresolved)", shape=box, color="#dc2626", fontcolor="#dc2626", penwidth=2];`,
+			`	"type:example.com/app/todo.Logger" [label="type\nexample.com/app/todo.Logger\n(ambiguous)", shape=box, color="#ea580c", fontcolor="#ea580c", penwidth=2];`,
+			`	"constructor:example.com/app/todo.NewService" -> "type:example.com/app/todo.Clock" [label="clock"];`,
+			`	"type:example.com/app/todo.Store" -> "constructor:example.com/app/todo.NewStore";`,
+		} {
+			if !contains(lines, expected) {
+				t.Errorf("expected the line %s, got:\n%s", expected, dot)
+			}
+		}
+	})
+}
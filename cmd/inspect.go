@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/YuukanOO/ease/pkg/parser"
+	"github.com/YuukanOO/ease/pkg/parser/api"
+)
+
+// Inspect parses configured packages and writes what ease understood from them on the
+// standard output, without generating anything. With the json format, the whole parse
+// result is written as a versioned parser.Document, including what extensions extracted.
+func Inspect(opts ...Option) error {
+	o := newOptions(opts)
+	result, err := o.parse()
+
+	if err != nil {
+		return err
+	}
+
+	err = o.report(result.Diagnostics())
+
+	// Diagnostics are part of the document which is useful to debug invalid directives
+	if o.format == formatJSON {
+		// Positions are relative to the module, or to the module cache for dependencies, so
+		// the document is the same on every machine
+		doc := parser.Inspect(result, o.parsers...).RelativeTo(o.moduleRoot(), moduleCache())
+
+		if writeErr := writeJSON(o.stdout, doc); writeErr != nil {
+			return writeErr
+		}
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return printInspect(o.stdout, result, apiSchema(o.parsers))
+}
+
+// Directory where dependencies are downloaded, empty if it could not be retrieved.
+func moduleCache() string {
+	out, err := exec.Command("go", "env", "GOMODCACHE").Output()
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// Writes a human readable summary of the given parse result.
+func printInspect(w io.Writer, result parser.Result, schema *api.API) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "Packages:")
+
+	for _, pkg := range result.Packages() {
+		fmt.Fprintf(tw, "  %s\t%s\n", pkg.Name(), pkg.Path())
+	}
+
+	if schema != nil {
+		fmt.Fprintln(tw, "\nEndpoints:")
+
+		for _, endpoint := range schema.Endpoints() {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", endpoint.Method(), endpoint.Path(), endpoint.Handler())
+		}
+
+		fmt.Fprintln(tw, "\nMiddlewares:")
+
+		for _, middleware := range schema.Middlewares() {
+			fmt.Fprintf(tw, "  %s\t%s\n", middleware.Name(), middleware.Handler())
+		}
+
+		fmt.Fprintln(tw, "\nVerifiers:")
+
+		for _, verifier := range schema.Verifiers() {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", verifier.Scheme(), verifier.Handler(), verifier.Principal().TypeExpr())
+		}
+
+		fmt.Fprintln(tw, "\nGroups:")
+
+		for _, group := range schema.Groups() {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", group.Key(), group.Path(), strings.Join(group.Tags(), ", "))
+		}
+	}
+
+	return tw.Flush()
+}
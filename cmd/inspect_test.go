@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/YuukanOO/ease/pkg/parser"
+)
+
+func TestInspect(t *testing.T) {
+	t.Run("should output positions relative to the module root as JSON", func(t *testing.T) {
+		dir := newModule(t, map[string]string{"todo/todo.go": todoSource})
+		code, stdout, stderr := runCLI("inspect", "-C", dir, "-q", "-no-cache", "-format", formatJSON, "./todo/...")
+
+		if code != exitOK {
+			t.Fatalf("expected the document to be printed, got %d: %s", code, stderr)
+		}
+
+		var doc parser.Document
+
+		if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(doc.Funcs) != 1 || len(doc.Funcs[0].Directives) != 1 {
+			t.Fatalf("expected the List func with its directive, got %s", stdout)
+		}
+
+		fn := doc.Funcs[0]
+
+		if fn.Position.File != "todo/todo.go" || fn.Position.Line != 4 || fn.Directives[0].Position.File != "todo/todo.go" {
+			t.Errorf("expected positions in todo/todo.go, got %v and %v", fn.Position, fn.Directives[0].Position)
+		}
+	})
+}
@@ -1,38 +1,12 @@
 package main
 
-import (
-	"errors"
-	"os"
-	"path/filepath"
-
-	"github.com/YuukanOO/ease/pkg/generator/gin"
-	"github.com/YuukanOO/ease/pkg/parser/api"
-)
-
-var ErrNoPackagesGiven = errors.New("missing packages names")
+import "os"
 
 func main() {
-	if len(os.Args) < 2 {
-		panic(ErrNoPackagesGiven)
-	}
-
-	pkgsToAnalyze := os.Args[1:]
-
-	wd, err := os.Getwd()
-
-	if err != nil {
-		panic(err)
+	c := &cli{
+		stdout: os.Stdout,
+		stderr: os.Stderr,
 	}
 
-	// Default parsers / generators
-	apiParser := api.New()
-	ginGenerator := gin.New(apiParser.Schema())
-
-	if err := Run(
-		WithPackages(pkgsToAnalyze...),
-		WithParsers(apiParser),
-		WithGenerators(filepath.Join(wd, "generated"), ginGenerator),
-	); err != nil {
-		panic(err)
-	}
+	os.Exit(c.run(os.Args[1:]))
 }
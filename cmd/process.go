@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"os/exec"
+	"time"
+)
+
+// Time given to a process to exit gracefully before being killed.
+const stopTimeout = 5 * time.Second
+
+// Process started with a shell command, such as the generated server in watch mode.
+type process struct {
+	cmd  *exec.Cmd
+	done chan error
+}
+
+func startProcess(command string, stdout, stderr io.Writer) (*process, error) {
+	cmd := shellCommand(command)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &process{cmd: cmd, done: make(chan error, 1)}
+
+	go func() { p.done <- cmd.Wait() }()
+
+	return p, nil
+}
+
+// Stops the process and its children, they are killed if they do not exit in time.
+func (p *process) stop() error {
+	select {
+	case <-p.done:
+		return nil // Already exited on its own
+	default:
+	}
+
+	if err := interrupt(p.cmd); err != nil {
+		return kill(p.cmd)
+	}
+
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(stopTimeout):
+		return kill(p.cmd)
+	}
+}
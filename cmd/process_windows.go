@@ -0,0 +1,12 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func shellCommand(command string) *exec.Cmd { return exec.Command("cmd", "/C", command) }
+
+// Interrupting a process is not supported on Windows, it is killed right away.
+func interrupt(*exec.Cmd) error { return errors.New("not supported") }
+func kill(cmd *exec.Cmd) error  { return cmd.Process.Kill() }
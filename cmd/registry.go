@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/YuukanOO/ease/pkg/generator"
+	"github.com/YuukanOO/ease/pkg/generator/gin"
+	"github.com/YuukanOO/ease/pkg/generator/plugin"
+	"github.com/YuukanOO/ease/pkg/parser"
+	"github.com/YuukanOO/ease/pkg/parser/api"
+)
+
+var (
+	ErrUnknownParser    = errors.New("unknown parser")
+	ErrUnknownGenerator = errors.New("unknown generator")
+	ErrMissingParser    = errors.New("generator needs a parser which is not enabled")
+)
+
+type (
+	// Enabled parsers by name, used by generators to retrieve what they need.
+	enabledParsers map[string]parser.Extension
+
+	generatorFactory struct {
+		needs string // Name of the parser needed by the generator
+		build func(enabledParsers, *generatorConfig) (generator.Extension, error)
+	}
+
+	// Generators sharing the same output directory.
+	outputGroup struct {
+		dir        string
+		generators []generator.Extension
+	}
+)
+
+// Every parser available from the command line.
+var parserFactories = map[string]func(*projectConfig) parser.Extension{
+	"api": func(cfg *projectConfig) parser.Extension {
+		opts := make([]api.Option, 0, len(cfg.Mounts))
+
+		for _, pkg := range sortedKeys(cfg.Mounts) {
+			opts = append(opts, api.WithMount(pkg, cfg.Mounts[pkg]))
+		}
+
+		return api.New(opts...)
+	},
+}
+
+// Every generator available from the command line.
+var generatorFactories = map[string]generatorFactory{
+	"gin": {
+		needs: "api",
+		build: func(p enabledParsers, cfg *generatorConfig) (generator.Extension, error) {
+			var opts []gin.Option
+
+			if cfg.Package != "" {
+				opts = append(opts, gin.WithPackageName(cfg.Package))
+			}
+
+			if cfg.Templates != "" {
+				tmpl, err := gin.ParseTemplates(cfg.Templates)
+
+				if err != nil {
+					return nil, fmt.Errorf("%w: generators.%s.templates: %w", ErrInvalidConfig, cfg.name, err)
+				}
+
+				opts = append(opts, gin.WithTemplates(tmpl))
+			}
+
+			return gin.New(p["api"].(api.Extension).Schema(), opts...), nil
+		},
+	},
+}
+
+// Builds parsers and generators from their configuration, generators are grouped by output
+// directory, relative ones being resolved from the given directory.
+func buildExtensions(cfg *projectConfig, parserNames []string, generators []*generatorConfig, dir string) ([]parser.Extension, []*outputGroup, error) {
+	var (
+		enabled = make(enabledParsers)
+		parsers = make([]parser.Extension, 0, len(parserNames))
+		groups  []*outputGroup
+	)
+
+	for _, name := range parserNames {
+		factory, found := parserFactories[name]
+
+		if !found {
+			return nil, nil, fmt.Errorf("%w %s, available ones: %s", ErrUnknownParser, name, available(parserFactories))
+		}
+
+		if _, exists := enabled[name]; !exists {
+			enabled[name] = factory(cfg)
+			parsers = append(parsers, enabled[name])
+		}
+	}
+
+	for _, cfg := range generators {
+		factory, found := generatorFactories[cfg.name]
+
+		// Unknown generators are looked up as ease-gen-<name> plugins
+		if !found {
+			if !isPlugin(cfg.name) {
+				return nil, nil, unknownGenerator(cfg.name)
+			}
+
+			groups = addToGroup(groups, cfg.outputDir(dir), buildPlugin(parsers, cfg))
+			continue
+		}
+
+		if _, exists := enabled[factory.needs]; !exists {
+			return nil, nil, fmt.Errorf("%w: %s needs %s", ErrMissingParser, cfg.name, factory.needs)
+		}
+
+		extension, err := factory.build(enabled, cfg)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		groups = addToGroup(groups, cfg.outputDir(dir), extension)
+	}
+
+	return parsers, groups, nil
+}
+
+// Builds the generator running the ease-gen-<name> plugin, what every enabled parser
+// extracted is sent to it.
+func buildPlugin(parsers []parser.Extension, cfg *generatorConfig) generator.Extension {
+	return plugin.New(cfg.name,
+		plugin.WithExtensions(parsers...),
+		plugin.WithPackageName(cfg.Package),
+		plugin.WithOptions(cfg.Options),
+	)
+}
+
+func isPlugin(name string) bool {
+	_, err := plugin.Lookup(name)
+	return err == nil
+}
+
+func unknownGenerator(name string) error {
+	return fmt.Errorf("%w %s, available ones: %s (or install %s%s in your PATH)",
+		ErrUnknownGenerator, name, available(generatorFactories), plugin.ExecutablePrefix, name)
+}
+
+// Adds the given generators to the group of the given directory.
+func addToGroup(groups []*outputGroup, dir string, extensions ...generator.Extension) []*outputGroup {
+	for _, group := range groups {
+		if group.dir == dir {
+			group.generators = append(group.generators, extensions...)
+			return groups
+		}
+	}
+
+	return append(groups, &outputGroup{dir: dir, generators: extensions})
+}
+
+// Retrieve the API schema built by the api parser if it is enabled.
+func apiSchema(parsers []parser.Extension) *api.API {
+	for _, p := range parsers {
+		if apiParser, ok := p.(api.Extension); ok {
+			return apiParser.Schema()
+		}
+	}
+
+	return nil
+}
+
+func available[T any](factories map[string]T) string {
+	return strings.Join(sortedKeys(factories), ", ")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/YuukanOO/ease/pkg/crypto"
+)
+
+const (
+	develVersion         = "(devel)"
+	versionHashLength    = 12
+	cacheDirPrefixLength = 12
+	disableCacheEnv      = "EASE_CACHE"
+)
+
+// Returns the ease version. Development builds have no version so a hash of the executable
+// is used instead, this way the cache is invalidated whenever ease itself changes.
+func easeVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != develVersion {
+		return info.Main.Version
+	}
+
+	executable, err := os.Executable()
+
+	if err != nil {
+		return develVersion
+	}
+
+	f, err := os.Open(executable)
+
+	if err != nil {
+		return develVersion
+	}
+
+	defer f.Close()
+
+	h := sha256.New()
+
+	if _, err = io.Copy(h, f); err != nil {
+		return develVersion
+	}
+
+	return develVersion + "-" + hex.EncodeToString(h.Sum(nil))[:versionHashLength]
+}
+
+// Key of cache entries, they are only valid for the same Go and ease versions.
+func cacheKey() string {
+	return runtime.Version() + " " + easeVersion()
+}
+
+// Returns the cache directory of the module in the given directory, or an empty string if
+// caching is disabled with EASE_CACHE=off or no cache directory is available.
+func defaultCacheDir(moduleDir string) string {
+	if os.Getenv(disableCacheEnv) == "off" {
+		return ""
+	}
+
+	dir, err := os.UserCacheDir()
+
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "ease", crypto.Prefix(moduleDir, cacheDirPrefixLength))
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/YuukanOO/ease/pkg/diagnostic"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Changes are batched until nothing happened for this long, editors usually write a file
+// in multiple steps.
+const debounceDelay = 200 * time.Millisecond
+
+type watcher struct {
+	*options
+
+	fsw     *fsnotify.Watcher
+	root    string          // Module root, only directories inside it are watched
+	watched map[string]bool // Watched directories
+	process *process        // Process started by the exec option, nil if not running
+}
+
+// Watch generates files once and then every time a Go file of a parsed package changes,
+// until the context is done. Diagnostics are reported after each run, errors do not stop
+// the watch. Since unchanged packages are restored from the cache, only modified ones are
+// parsed again.
+func Watch(ctx context.Context, opts ...Option) error {
+	fsw, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		return err
+	}
+
+	defer fsw.Close()
+
+	w := &watcher{
+		options: newOptions(opts),
+		fsw:     fsw,
+		watched: make(map[string]bool),
+	}
+
+	w.root = w.moduleRoot()
+
+	defer w.stopProcess()
+
+	w.generate()
+
+	if w.verbosity > VerbosityQuiet {
+		fmt.Fprintf(w.stderr, "ease: watching %d directories for changes, press Ctrl+C to stop\n", len(w.watched))
+	}
+
+	for {
+		changed, err := w.wait(ctx)
+
+		if err != nil || changed == "" {
+			return err
+		}
+
+		if w.verbosity > VerbosityQuiet {
+			fmt.Fprintf(w.stderr, "ease: %s changed, generating again\n", changed)
+		}
+
+		w.generate()
+	}
+}
+
+// Runs the generation, watches directories of parsed packages of the module and restarts
+// the process if everything went well.
+func (w *watcher) generate() {
+	start := time.Now()
+	result, err := w.run()
+
+	// Packages which failed to load have no position so directories watched so far are kept
+	if result != nil {
+		for _, pkg := range result.Packages() {
+			if pkg.IsDeclared() {
+				w.watch(filepath.Dir(pkg.Position().Filename))
+			}
+		}
+	}
+
+	if err != nil {
+		// Diagnostics have already been reported
+		if !errors.Is(err, diagnostic.ErrFailed) {
+			fmt.Fprintf(w.stderr, "ease: %v\n", err)
+		}
+
+		return
+	}
+
+	w.logf("done in %s", time.Since(start))
+
+	if w.exec != "" {
+		w.restartProcess()
+	}
+}
+
+// Watches the given directory unless it is outside of the module, such as dependencies
+// in the module cache, or ignored.
+func (w *watcher) watch(dir string) {
+	if w.watched[dir] || !isInside(dir, w.root) || isIgnored(dir, w.ignoredDirs()) {
+		return
+	}
+
+	if err := w.fsw.Add(dir); err != nil {
+		fmt.Fprintf(w.stderr, "ease: could not watch %s: %v\n", dir, err)
+		return
+	}
+
+	w.watched[dir] = true
+}
+
+// Watches the given created directory and the ones inside it, since they may contain new
+// packages, and returns true if they contain Go files. Like go list, directories named
+// testdata or starting with a dot or an underscore are skipped.
+func (w *watcher) watchCreated(dir string) bool {
+	var hasGoFiles bool
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			hasGoFiles = hasGoFiles || filepath.Ext(path) == ".go"
+			return nil
+		}
+
+		if name := entry.Name(); name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			return filepath.SkipDir
+		}
+
+		w.watch(path)
+
+		return nil
+	})
+
+	// The directory may have been removed in the meantime
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(w.stderr, "ease: could not watch %s: %v\n", dir, err)
+	}
+
+	return hasGoFiles
+}
+
+// Waits for a Go file to change and returns its path once no other change happened for
+// debounceDelay. Returns an empty path when the context is done.
+func (w *watcher) wait(ctx context.Context) (string, error) {
+	return debounce(ctx, w.fsw.Events, w.fsw.Errors, debounceDelay, w.relevant)
+}
+
+// Checks if the given event should trigger a new generation, watching created directories
+// so files added to them later are noticed too.
+func (w *watcher) relevant(event fsnotify.Event) bool {
+	ignored := w.ignoredDirs()
+
+	if info, err := os.Stat(event.Name); event.Has(fsnotify.Create) && err == nil && info.IsDir() {
+		return !isIgnored(event.Name, ignored) && w.watchCreated(event.Name)
+	}
+
+	return isRelevant(event, ignored)
+}
+
+// Output and cache directories, ease writes to them so their changes must be ignored.
+func (w *watcher) ignoredDirs() []string {
+	ignored := []string{w.cacheDir}
+
+	for _, output := range w.outputs {
+		ignored = append(ignored, output.dir)
+	}
+
+	return ignored
+}
+
+// Waits for relevant events and returns the sorted, comma separated, names of changed
+// files once no other relevant event happened for the given delay. Returns an empty
+// string when the context is done or events are closed.
+func debounce(
+	ctx context.Context,
+	events <-chan fsnotify.Event,
+	errs <-chan error,
+	delay time.Duration,
+	relevant func(fsnotify.Event) bool,
+) (string, error) {
+	var (
+		changed []string
+		timer   = time.NewTimer(delay)
+	)
+
+	// The timer is only started by the first change
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", nil
+		case event, ok := <-events:
+			if !ok {
+				return "", nil
+			}
+
+			if !relevant(event) {
+				continue
+			}
+
+			changed = append(changed, event.Name)
+			timer.Reset(delay)
+		case err, ok := <-errs:
+			if !ok {
+				return "", nil
+			}
+
+			return "", err
+		case <-timer.C:
+			sort.Strings(changed)
+			return strings.Join(dedupe(changed), ", "), nil
+		}
+	}
+}
+
+// Only changes of Go files outside of ignored directories trigger a new generation.
+func isRelevant(event fsnotify.Event, ignored []string) bool {
+	if event.Op == fsnotify.Chmod || filepath.Ext(event.Name) != ".go" {
+		return false
+	}
+
+	return !isIgnored(filepath.Dir(event.Name), ignored)
+}
+
+// Checks if the given directory is one of the ignored ones or is inside one of them.
+func isIgnored(dir string, ignored []string) bool {
+	for _, root := range ignored {
+		if root != "" && isInside(dir, root) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Checks if the given directory is the root one or is inside it.
+func isInside(dir, root string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+func (w *watcher) restartProcess() {
+	w.stopProcess()
+
+	w.logf("starting %s", w.exec)
+
+	process, err := startProcess(w.exec, w.stdout, w.stderr)
+
+	if err != nil {
+		fmt.Fprintf(w.stderr, "ease: could not start %s: %v\n", w.exec, err)
+		return
+	}
+
+	w.process = process
+}
+
+func (w *watcher) stopProcess() {
+	if w.process == nil {
+		return
+	}
+
+	if err := w.process.stop(); err != nil {
+		w.logf("stopping %s: %v", w.exec, err)
+	}
+
+	w.process = nil
+}
+
+// Removes consecutive duplicates of the given sorted values.
+func dedupe(values []string) []string {
+	result := values[:0]
+
+	for i, value := range values {
+		if i == 0 || value != values[i-1] {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
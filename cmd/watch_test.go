@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatchFilter(t *testing.T) {
+	var (
+		root    = filepath.FromSlash("/module")
+		ignored = []string{filepath.Join(root, "generated"), "", filepath.FromSlash("/cache/ease")}
+	)
+
+	for _, test := range []struct {
+		name     string
+		event    fsnotify.Event
+		expected bool
+	}{
+		{"written Go file", fsnotify.Event{Name: "todo/todo.go", Op: fsnotify.Write}, true},
+		{"created Go file", fsnotify.Event{Name: "todo.go", Op: fsnotify.Create}, true},
+		{"removed Go file", fsnotify.Event{Name: "todo/todo.go", Op: fsnotify.Remove}, true},
+		{"permissions change", fsnotify.Event{Name: "todo/todo.go", Op: fsnotify.Chmod}, false},
+		{"other file", fsnotify.Event{Name: "todo/README.md", Op: fsnotify.Write}, false},
+		{"file of the output directory", fsnotify.Event{Name: "generated/server.go", Op: fsnotify.Write}, false},
+		{"file inside the output directory", fsnotify.Event{Name: "generated/sub/server.go", Op: fsnotify.Create}, false},
+		{"file of a sibling with the same prefix", fsnotify.Event{Name: "generated2/server.go", Op: fsnotify.Write}, true},
+		{"file of the cache directory", fsnotify.Event{Name: "../cache/ease/todo.go", Op: fsnotify.Write}, false},
+	} {
+		t.Run("should filter "+test.name, func(t *testing.T) {
+			event := test.event
+			event.Name = filepath.Join(root, filepath.FromSlash(event.Name))
+
+			if relevant := isRelevant(event, ignored); relevant != test.expected {
+				t.Errorf("expected %s to be relevant: %t, got %t", event, test.expected, relevant)
+			}
+		})
+	}
+}
+
+func TestWatchDebounce(t *testing.T) {
+	const delay = 100 * time.Millisecond
+
+	relevant := func(event fsnotify.Event) bool { return isRelevant(event, []string{"generated"}) }
+
+	t.Run("should batch changes until none happened for the delay", func(t *testing.T) {
+		events := make(chan fsnotify.Event)
+
+		go func() {
+			// Changes are closer than the delay so they must all be reported at once
+			for i := 0; i < 5; i++ {
+				events <- fsnotify.Event{Name: fmt.Sprintf("file%d.go", i%3), Op: fsnotify.Write}
+				time.Sleep(delay / 10)
+			}
+		}()
+
+		changed, err := debounce(context.Background(), events, nil, delay, relevant)
+
+		if err != nil || changed != "file0.go, file1.go, file2.go" {
+			t.Errorf("expected every changed file once, got %q, %v", changed, err)
+		}
+	})
+
+	t.Run("should not wake up on changes of ignored files", func(t *testing.T) {
+		events := make(chan fsnotify.Event, 3)
+		events <- fsnotify.Event{Name: "generated/server.go", Op: fsnotify.Write}
+		events <- fsnotify.Event{Name: "todo.go", Op: fsnotify.Chmod}
+		events <- fsnotify.Event{Name: "notes.txt", Op: fsnotify.Write}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 4*delay)
+		defer cancel()
+
+		if changed, err := debounce(ctx, events, nil, delay, relevant); changed != "" || err != nil {
+			t.Errorf("expected the generator own writes to be ignored, got %q, %v", changed, err)
+		}
+	})
+
+	t.Run("should stop when events are closed", func(t *testing.T) {
+		events := make(chan fsnotify.Event)
+		close(events)
+
+		if changed, err := debounce(context.Background(), events, nil, delay, relevant); changed != "" || err != nil {
+			t.Errorf("expected nothing, got %q, %v", changed, err)
+		}
+	})
+
+	t.Run("should return watcher errors", func(t *testing.T) {
+		errs := make(chan error, 1)
+		errs <- errors.New("overflow")
+
+		if _, err := debounce(context.Background(), nil, errs, delay, relevant); err == nil || err.Error() != "overflow" {
+			t.Errorf("expected the watcher error, got %v", err)
+		}
+	})
+}
+
+func TestWatchDirectories(t *testing.T) {
+	fsw, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	defer fsw.Close()
+
+	root := t.TempDir()
+	w := &watcher{
+		options: newOptions([]Option{WithOutput(io.Discard, io.Discard), WithGenerators(filepath.Join(root, "generated"))}),
+		fsw:     fsw,
+		root:    root,
+		watched: make(map[string]bool),
+	}
+
+	mkdir := func(t *testing.T, paths ...string) {
+		t.Helper()
+
+		for _, path := range paths {
+			if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(path)), 0o755); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+
+	expectWatched := func(t *testing.T, expected ...string) {
+		t.Helper()
+
+		watched := make(map[string]bool)
+
+		for _, path := range expected {
+			watched[filepath.Join(root, filepath.FromSlash(path))] = true
+		}
+
+		if !reflect.DeepEqual(w.watched, watched) {
+			t.Errorf("expected %v to be watched, got %v", watched, w.watched)
+		}
+	}
+
+	t.Run("should only watch directories of the module", func(t *testing.T) {
+		mkdir(t, "todo", "generated")
+
+		w.watch(filepath.Join(root, "todo"))
+		w.watch(filepath.Join(root, "generated"))
+		w.watch(t.TempDir())
+
+		expectWatched(t, "todo")
+	})
+
+	t.Run("should watch created directories and the packages inside them", func(t *testing.T) {
+		mkdir(t, "users/store", "users/.git", "users/testdata", "empty", "generated/sub")
+
+		if err := os.WriteFile(filepath.Join(root, "users", "store", "store.go"), []byte("package store\n"), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for path, expected := range map[string]bool{
+			"users":         true,
+			"empty":         false,
+			"generated/sub": false,
+		} {
+			event := fsnotify.Event{Name: filepath.Join(root, filepath.FromSlash(path)), Op: fsnotify.Create}
+
+			if relevant := w.relevant(event); relevant != expected {
+				t.Errorf("expected the creation of %s to be relevant: %t, got %t", path, expected, relevant)
+			}
+		}
+
+		expectWatched(t, "todo", "users", "users/store", "empty")
+	})
+}
@@ -0,0 +1,48 @@
+package todo
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type (
+	// Represents an authenticated user.
+	User struct {
+		Name string `json:"name"`
+	}
+
+	Authenticator struct {
+		logger Logger
+	}
+)
+
+// Builds up a new Authenticator.
+func NewAuthenticator(l Logger) *Authenticator {
+	return &Authenticator{
+		logger: l,
+	}
+}
+
+// Verifies the given bearer token. This is a dummy implementation which accepts any
+// token and use it as the user name.
+//
+// ease:verifier scheme=bearer
+func (a *Authenticator) Verify(ctx context.Context, token string, scopes []string) (*User, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	a.logger.Log("authenticated", token, "with scopes", scopes)
+
+	return &User{Name: token}, nil
+}
+
+// Returns the currently authenticated user.
+//
+// ease:api path=/api/me
+// ease:auth scheme=bearer scopes=profile:read
+func Me(user *User) *User {
+	return user
+}
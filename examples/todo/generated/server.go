@@ -2,37 +2,51 @@
 package main
 
 import (
-	context_ea7792 "context"
-	ease_external_example_e02a9c "github.com/YuukanOO/ease-external-example"
-	todo_ca7678 "github.com/YuukanOO/ease/todo"
-	"github.com/gin-gonic/gin"
+	"context"
 	"net/http"
 	"strconv"
+	"strings"
+
+	easeexternalexample "github.com/YuukanOO/ease-external-example"
+	"github.com/YuukanOO/ease/todo"
+	"github.com/gin-gonic/gin"
 )
 
 type Server struct {
-	Router             *gin.Engine
-	Logger_9c64fc      todo_ca7678.Logger
-	TodoService_9abf69 *todo_ca7678.TodoService
+	Router        *gin.Engine
+	logger        todo.Logger
+	store         *todo.Store[*todo.Todo]
+	todoService   *todo.TodoService
+	authenticator *todo.Authenticator
 }
 
 func NewServer() (s *Server, err error) {
 	s = &Server{
 		Router: gin.Default(),
 	}
-	s.Logger_9c64fc = todo_ca7678.NewLogger()
-	s.TodoService_9abf69 = todo_ca7678.NewTodoService(
-		s.Logger_9c64fc,
+	s.logger = todo.NewLogger()
+	s.store = todo.NewStore[*todo.Todo]()
+	s.todoService = todo.NewTodoService(
+		s.logger,
+		s.store,
 	)
+	s.authenticator = todo.NewAuthenticator(
+		s.logger,
+	)
+
+	group_313ad7 := s.Router.Group("/api/todos")
 
-	s.Router.POST("/api/todos", s.Create_9e7e22)
-	s.Router.GET("/api/todos", s.List_765091)
-	s.Router.PUT("/api/todos/:id", s.Update_0cdc62)
-	s.Router.DELETE("/api/todos/:id", s.Delete_df53ad)
-	s.Router.GET("/api/without-params", s.WithoutParams_5fd7e9)
-	s.Router.GET("/api/raw", gin.WrapF(s.TodoService_9abf69.RawEndpoint))
-	s.Router.GET("/api/raw-without-receiver", gin.WrapF(todo_ca7678.RawWithoutReceiver))
-	s.Router.GET("/api/_health", s.HealthCheck_0e096a)
+	s.Router.GET("/api/me", Middleware(s.audit), s.me)
+	group_313ad7.POST("", Middleware(s.audit), s.create)
+	group_313ad7.GET("", Middleware(s.audit), s.list)
+	group_313ad7.GET("/page", Middleware(s.audit), s.paginate)
+	group_313ad7.PUT("/:id", Middleware(s.audit), s.update)
+	group_313ad7.PATCH("/:id", Middleware(s.audit), s.updateTodo)
+	group_313ad7.DELETE("/:id", Middleware(s.audit), s.delete)
+	group_313ad7.GET("/without-params", Middleware(s.audit), s.withoutParams)
+	group_313ad7.GET("/raw", Middleware(s.audit), gin.WrapF(s.todoService.RawEndpoint))
+	s.Router.GET("/api/raw-without-receiver", Middleware(s.audit), gin.WrapF(todo.RawWithoutReceiver))
+	s.Router.GET("/api/_health", s.healthCheck)
 
 	return s, nil
 }
@@ -51,13 +65,50 @@ func main() {
 	s.Listen()
 }
 
-func (s *Server) Create_9e7e22(c *gin.Context) {
-	var ctx context_ea7792.Context = c.Request.Context()
-	var cmd todo_ca7678.TodoCreateCommand
+func (s *Server) audit(next http.Handler) http.Handler {
+	return todo.Audit(
+		s.logger,
+		next,
+	)
+}
+
+func (s *Server) bearer(c *gin.Context, scopes ...string) (principal *todo.User, ok bool) {
+	credentials := BearerToken(c)
+
+	if credentials == "" {
+		c.Header("WWW-Authenticate", "Bearer")
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	principal, err := s.authenticator.Verify(c.Request.Context(), credentials, scopes)
+
+	if err != nil {
+		HandleAuthError(c, err)
+		return
+	}
+
+	return principal, true
+}
+
+func (s *Server) me(c *gin.Context) {
+	user, authenticated := s.bearer(c, "profile:read")
+	if !authenticated {
+		return
+	}
+	result_5a2298 := todo.Me(
+		user,
+	)
+	c.JSON(http.StatusOK, result_5a2298)
+}
+
+func (s *Server) create(c *gin.Context) {
+	var ctx context.Context = c.Request.Context()
+	var cmd todo.TodoCreateCommand
 	if !Bind(c, &cmd) {
 		return
 	}
-	result_5a2298, err := s.TodoService_9abf69.Create(
+	result_5a2298, err := s.todoService.Create(
 		ctx,
 		cmd,
 	)
@@ -68,9 +119,9 @@ func (s *Server) Create_9e7e22(c *gin.Context) {
 	c.JSON(http.StatusCreated, result_5a2298)
 }
 
-func (s *Server) List_765091(c *gin.Context) {
-	var ctx context_ea7792.Context = c.Request.Context()
-	result_5a2298, err := s.TodoService_9abf69.List(
+func (s *Server) list(c *gin.Context) {
+	var ctx context.Context = c.Request.Context()
+	result_5a2298, err := s.todoService.List(
 		ctx,
 	)
 	if err != nil {
@@ -80,14 +131,31 @@ func (s *Server) List_765091(c *gin.Context) {
 	c.JSON(http.StatusOK, result_5a2298)
 }
 
-func (s *Server) Update_0cdc62(c *gin.Context) {
-	var ctx context_ea7792.Context = c.Request.Context()
+func (s *Server) paginate(c *gin.Context) {
+	var ctx context.Context = c.Request.Context()
+	var p todo.Pagination
+	if !Bind(c, &p) {
+		return
+	}
+	result_5a2298, err := s.todoService.Paginate(
+		ctx,
+		p,
+	)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result_5a2298)
+}
+
+func (s *Server) update(c *gin.Context) {
+	var ctx context.Context = c.Request.Context()
 	var id uint = ParamToInt[uint](c, "id")
-	var cmd todo_ca7678.TodoUpdateCommand
+	var cmd todo.TodoUpdateCommand
 	if !Bind(c, &cmd) {
 		return
 	}
-	result_5a2298, err := s.TodoService_9abf69.Update(
+	result_5a2298, err := s.todoService.Update(
 		ctx,
 		id,
 		cmd,
@@ -99,9 +167,28 @@ func (s *Server) Update_0cdc62(c *gin.Context) {
 	c.JSON(http.StatusOK, result_5a2298)
 }
 
-func (s *Server) Delete_df53ad(c *gin.Context) {
+func (s *Server) updateTodo(c *gin.Context) {
+	var ctx context.Context = c.Request.Context()
 	var id uint = ParamToInt[uint](c, "id")
-	err := s.TodoService_9abf69.Delete(
+	var cmd todo.TodoUpdateCommand
+	if !Bind(c, &cmd) {
+		return
+	}
+	result_5a2298, err := s.todoService.Update(
+		ctx,
+		id,
+		cmd,
+	)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result_5a2298)
+}
+
+func (s *Server) delete(c *gin.Context) {
+	var id uint = ParamToInt[uint](c, "id")
+	err := s.todoService.Delete(
 		id,
 	)
 	if err != nil {
@@ -111,13 +198,13 @@ func (s *Server) Delete_df53ad(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
-func (s *Server) WithoutParams_5fd7e9(c *gin.Context) {
-	s.TodoService_9abf69.WithoutParams()
+func (s *Server) withoutParams(c *gin.Context) {
+	s.todoService.WithoutParams()
 	c.Status(http.StatusNoContent)
 }
 
-func (s *Server) HealthCheck_0e096a(c *gin.Context) {
-	result_5a2298 := ease_external_example_e02a9c.HealthCheck()
+func (s *Server) healthCheck(c *gin.Context) {
+	result_5a2298 := easeexternalexample.HealthCheck()
 	c.JSON(http.StatusOK, result_5a2298)
 }
 
@@ -144,6 +231,49 @@ func HandleError(c *gin.Context, err error) {
 	c.JSON(httpErr.Status(), err)
 }
 
+// Middleware adapts a standard net/http middleware to be used by gin.
+func Middleware(m func(http.Handler) http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		called := false
+
+		m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			c.Request = r
+			c.Next()
+		})).ServeHTTP(c.Writer, c.Request)
+
+		if !called {
+			c.Abort()
+		}
+	}
+}
+
+// BearerToken extracts the token of the Authorization header if any.
+func BearerToken(c *gin.Context) string {
+	const prefix = "Bearer "
+
+	header := c.GetHeader("Authorization")
+
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+
+	return header[len(prefix):]
+}
+
+// HandleAuthError aborts the request with the status of the error if it implements HttpError,
+// with a 401 otherwise.
+func HandleAuthError(c *gin.Context, err error) {
+	if _, implementHttpErr := err.(HttpError); implementHttpErr {
+		HandleError(c, err)
+		c.Abort()
+		return
+	}
+
+	c.Error(err)
+	c.AbortWithStatus(http.StatusUnauthorized)
+}
+
 func Bind[T any](c *gin.Context, target *T) bool {
 	if err := c.ShouldBind(target); err != nil {
 		c.AbortWithError(http.StatusUnprocessableEntity, err)
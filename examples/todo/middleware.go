@@ -0,0 +1,13 @@
+package todo
+
+import "net/http"
+
+// Logs every request handled by the next handler.
+//
+// ease:middleware name=audit
+func Audit(l Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Log(r.Method, r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,13 +1,14 @@
 // Simple package to test out ease capabilities.
+//
+// ease:use audit
 package todo
 
-//go:generate go run github.com/YuukanOO/ease/cmd github.com/YuukanOO/ease/todo... github.com/YuukanOO/ease-external-example
+//go:generate go run github.com/YuukanOO/ease/cmd generate
 
 import (
 	contextalias "context"
 	"errors"
 	"net/http"
-	"sync"
 )
 
 var ErrNotFound = errors.New("not found")
@@ -15,17 +16,19 @@ var ErrNotFound = errors.New("not found")
 type (
 	SomeInterface interface{}
 
+	// Exposes todos use cases.
+	//
+	// ease:group prefix=/api/todos tag=todos
 	TodoService struct {
-		todos  []*Todo
-		mu     sync.Mutex
+		todos  *Store[*Todo]
 		logger Logger
 	}
 )
 
 // Builds up a new TodoService.
-func NewTodoService(l Logger) *TodoService {
+func NewTodoService(l Logger, todos *Store[*Todo]) *TodoService {
 	return &TodoService{
-		todos:  make([]*Todo, 0),
+		todos:  todos,
 		logger: l,
 	}
 }
@@ -36,26 +39,30 @@ type TodoCreateCommand struct {
 
 // Creates a new todo with the given text content.
 //
-//ease:api method=POST path=/api/todos
+// ease:api method=POST \
+// summary="Create a todo"
 func (s *TodoService) Create(ctx contextalias.Context, cmd TodoCreateCommand) (*Todo, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	todo := &Todo{
-		ID:        uint(len(s.todos) + 1),
-		Text:      cmd.Text,
-		Completed: false,
-	}
-	s.todos = append(s.todos, todo)
-
-	return todo, nil
+	return s.todos.Add(func(id int) *Todo {
+		return &Todo{
+			ID:        uint(id),
+			Text:      cmd.Text,
+			Completed: false,
+		}
+	}), nil
 }
 
 // Lists all todos.
 //
-//ease:api method=GET path=/api/todos
+//ease:api method=GET
 func (s *TodoService) List(ctx contextalias.Context) ([]*Todo, error) {
-	return s.todos, nil
+	return s.todos.All(), nil
+}
+
+// Lists todos a page at a time.
+//
+//ease:api method=GET path=/page
+func (s *TodoService) Paginate(ctx contextalias.Context, p Pagination) (Page[*Todo], error) {
+	return s.todos.Page(p), nil
 }
 
 type TodoUpdateCommand struct {
@@ -64,9 +71,10 @@ type TodoUpdateCommand struct {
 
 // Updates the todo with the given id.
 //
-//ease:api method=PUT path=/api/todos/:id
+//ease:api method=PUT path=/:id
+//ease:api method=PATCH path=/:id
 func (s *TodoService) Update(ctx contextalias.Context, id uint, cmd TodoUpdateCommand) (*Todo, error) {
-	for _, todo := range s.todos {
+	for _, todo := range s.todos.All() {
 		if todo.ID == id {
 			todo.Completed = cmd.Completed
 			return todo, nil
@@ -78,17 +86,17 @@ func (s *TodoService) Update(ctx contextalias.Context, id uint, cmd TodoUpdateCo
 
 var ErrOperationNotImplemented = NewAppError("operation_not_supported")
 
-// ease:api method=DELETE path=/api/todos/:id
+// ease:api method=DELETE path=/:id
 func (s *TodoService) Delete(id uint) error {
 	return ErrOperationNotImplemented
 }
 
-// ease:api path=/api/without-params
+// ease:api path=/without-params
 func (s *TodoService) WithoutParams() {
 	s.logger.Log("without params nor return value")
 }
 
-// ease:api method=GET path=/api/raw
+// ease:api method=GET path=/raw
 func (s *TodoService) RawEndpoint(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(204)
 }
@@ -0,0 +1,66 @@
+package todo
+
+import "sync"
+
+type (
+	// In memory store shared by services.
+	Store[T any] struct {
+		mu    sync.Mutex
+		items []T
+	}
+
+	// Shared pagination wrapper returned by listing endpoints.
+	Page[T any] struct {
+		Items []T `json:"items"`
+		Total int `json:"total"`
+	}
+
+	Pagination struct {
+		Offset int `form:"offset"`
+		Limit  int `form:"limit"`
+	}
+)
+
+// Builds up a new empty store, the resolver instantiates it for each required type.
+func NewStore[T any]() *Store[T] {
+	return &Store[T]{}
+}
+
+// Appends the item built by the given function, which receives the new store size.
+func (s *Store[T]) Add(build func(int) T) T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := build(len(s.items) + 1)
+	s.items = append(s.items, item)
+
+	return item
+}
+
+// Returns a snapshot of every item.
+func (s *Store[T]) All() []T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]T(nil), s.items...)
+}
+
+// Returns the requested page of items.
+func (s *Store[T]) Page(p Pagination) Page[T] {
+	items := s.All()
+	page := Page[T]{Total: len(items), Items: []T{}}
+
+	if p.Offset < 0 || p.Offset >= len(items) {
+		return page
+	}
+
+	end := len(items)
+
+	if p.Limit > 0 && p.Offset+p.Limit < end {
+		end = p.Offset + p.Limit
+	}
+
+	page.Items = items[p.Offset:end]
+
+	return page
+}
@@ -2,7 +2,12 @@ module github.com/YuukanOO/ease
 
 go 1.20
 
-require golang.org/x/tools v0.9.3
+require (
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/pelletier/go-toml/v2 v2.0.8
+	golang.org/x/tools v0.9.3
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require golang.org/x/mod v0.10.0 // indirect
 
@@ -1,10 +1,17 @@
 package collection
 
-import "sync"
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+)
 
-// Represents a unique set of objects.
+// Represents a unique set of objects which keeps the insertion order. It is safe for
+// concurrent use, items returned by the set are snapshots which may be iterated while
+// other goroutines keep on adding items.
 type Set[T any] struct {
-	mu      sync.Mutex
+	mu      sync.RWMutex
+	keys    []string
 	items   []T
 	indexes map[string]int
 }
@@ -18,33 +25,109 @@ func NewSet[T any]() *Set[T] {
 
 // Set the given item for the given key if it does not exist. Returns the item.
 func (s *Set[T]) Set(key string, item T) T {
-	if idx, found := s.indexes[key]; found {
-		return s.items[idx]
-	}
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.indexes[key] = len(s.items)
-	s.items = append(s.items, item)
-	return item
+	return s.SetFunc(key, func() T { return item })
 }
 
 // Same as Set but build the item only if not already found in the set to prevent
-// unneeded allocations.
+// unneeded allocations. The builder is called at most once per key, even when multiple
+// goroutines set the same key concurrently, so it must not use the set itself.
 func (s *Set[T]) SetFunc(key string, item func() T) T {
-	if idx, found := s.indexes[key]; found {
-		return s.items[idx]
+	if existing, found := s.Get(key); found {
+		return existing
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// Another goroutine may have set it while the lock was released
+	if idx, found := s.indexes[key]; found {
+		return s.items[idx]
+	}
+
 	created := item()
 	s.indexes[key] = len(s.items)
+	s.keys = append(s.keys, key)
 	s.items = append(s.items, created)
 	return created
 }
 
-// Retrieve all items inside the set.
-func (s *Set[T]) Items() []T { return s.items }
+// Retrieve the item with the given key if it exists.
+func (s *Set[T]) Get(key string) (T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	idx, found := s.indexes[key]
+
+	if !found {
+		var zero T
+		return zero, false
+	}
+
+	return s.items[idx], true
+}
+
+// Checks if an item exists for the given key.
+func (s *Set[T]) Has(key string) bool {
+	_, found := s.Get(key)
+	return found
+}
+
+// Returns the number of items inside the set.
+func (s *Set[T]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.items)
+}
+
+// Retrieve a snapshot of all items inside the set in insertion order.
+func (s *Set[T]) Items() []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]T(nil), s.items...)
+}
+
+// MarshalJSON encodes the set as the array of its items in insertion order.
+func (s *Set[T]) MarshalJSON() ([]byte, error) { return json.Marshal(s.Items()) }
+
+// Retrieve a snapshot of all keys inside the set in insertion order.
+func (s *Set[T]) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]string(nil), s.keys...)
+}
+
+// Retrieve a snapshot of all items sorted by their key. Unlike Items, the order does not
+// depend on the insertion order so it stays the same when items are added concurrently.
+func (s *Set[T]) Sorted() []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := append([]string(nil), s.keys...)
+	sort.Strings(keys)
+
+	items := make([]T, len(keys))
+
+	for i, key := range keys {
+		items[i] = s.items[s.indexes[key]]
+	}
+
+	return items
+}
+
+// Calls fn for each item of a snapshot of the set in insertion order, stopping as soon
+// as it returns false. The set may be modified by fn.
+func (s *Set[T]) Range(fn func(key string, item T) bool) {
+	s.mu.RLock()
+	keys := append([]string(nil), s.keys...)
+	items := append([]T(nil), s.items...)
+	s.mu.RUnlock()
+
+	for i, item := range items {
+		if !fn(keys[i], item) {
+			return
+		}
+	}
+}
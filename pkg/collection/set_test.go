@@ -1,6 +1,11 @@
 package collection_test
 
 import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/YuukanOO/ease/pkg/collection"
@@ -50,4 +55,90 @@ func TestSet(t *testing.T) {
 			t.Errorf("expected lazy function to be called once, got %d", callCount)
 		}
 	})
+
+	t.Run("should be encoded as an array of its items", func(t *testing.T) {
+		s := collection.NewSet[int]()
+
+		s.Set("b", 2)
+		s.Set("a", 1)
+
+		data, err := json.Marshal(s)
+
+		if err != nil || string(data) != "[2,1]" {
+			t.Errorf("expected [2,1], got %s, %v", data, err)
+		}
+	})
+
+	t.Run("should retrieve items by key", func(t *testing.T) {
+		s := collection.NewSet[string]()
+
+		s.Set("foo", "bar")
+
+		if item, found := s.Get("foo"); !found || item != "bar" {
+			t.Errorf("expected to find item 'bar', got '%s'", item)
+		}
+
+		if s.Has("baz") {
+			t.Error("expected key 'baz' to be missing")
+		}
+	})
+
+	t.Run("should return snapshots not affected by later additions", func(t *testing.T) {
+		s := collection.NewSet[string]()
+
+		s.Set("b", "1")
+		s.Set("a", "2")
+
+		items := s.Items()
+		s.Set("c", "3")
+
+		if len(items) != 2 {
+			t.Errorf("expected snapshot to contain 2 items, got %d", len(items))
+		}
+
+		if sorted := s.Sorted(); sorted[0] != "2" || sorted[1] != "1" || sorted[2] != "3" {
+			t.Errorf("expected items to be sorted by key, got %v", sorted)
+		}
+
+		var keys []string
+
+		s.Range(func(key string, _ string) bool {
+			keys = append(keys, key)
+			s.Set(key+key, key) // Modifying the set while iterating should not deadlock
+			return true
+		})
+
+		if strings.Join(keys, ",") != "b,a,c" {
+			t.Errorf("expected keys in insertion order, got %v", keys)
+		}
+	})
+
+	t.Run("should be safe for concurrent use", func(t *testing.T) {
+		var (
+			s     = collection.NewSet[int]()
+			wg    sync.WaitGroup
+			calls atomic.Int32
+		)
+
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				s.SetFunc(strconv.Itoa(i%10), func() int {
+					calls.Add(1)
+					return i
+				})
+				s.Items()
+				s.Get(strconv.Itoa(i % 10))
+			}(i)
+		}
+
+		wg.Wait()
+
+		if s.Len() != 10 || calls.Load() != 10 {
+			t.Errorf("expected 10 items built once each, got %d items and %d calls", s.Len(), calls.Load())
+		}
+	})
 }
@@ -0,0 +1,189 @@
+package diagnostic
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+// Code used when reporting an error which is not a diagnostic.
+const CodeInternal = "internal"
+
+var ErrFailed = errors.New("one or more errors were reported")
+
+type (
+	Severity uint8 // How bad a diagnostic is
+
+	// Represents a single problem found in the source code. It implements the error interface
+	// so functions can return a diagnostic as an error and let the caller report it.
+	Diagnostic struct {
+		Severity Severity
+		Position token.Position
+		Code     string // Short identifier of the problem, such as duplicate-route
+		Message  string
+		Related  []Related
+	}
+
+	// Another location related to a diagnostic.
+	Related struct {
+		Position token.Position
+		Message  string
+	}
+
+	// Collection of diagnostics which can be appended to from multiple goroutines.
+	Diagnostics struct {
+		mu    sync.Mutex
+		items []*Diagnostic
+	}
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// Builds a new error diagnostic.
+func Errorf(pos token.Position, code string, format string, args ...any) *Diagnostic {
+	return &Diagnostic{
+		Severity: SeverityError,
+		Position: pos,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// Builds a new warning diagnostic.
+func Warnf(pos token.Position, code string, format string, args ...any) *Diagnostic {
+	d := Errorf(pos, code, format, args...)
+	d.Severity = SeverityWarning
+	return d
+}
+
+// Adds a related position to the diagnostic and returns it.
+func (d *Diagnostic) WithRelated(pos token.Position, message string) *Diagnostic {
+	d.Related = append(d.Related, Related{pos, message})
+	return d
+}
+
+func (d *Diagnostic) Error() string {
+	var b strings.Builder
+
+	if d.Position.IsValid() {
+		b.WriteString(d.Position.String())
+		b.WriteString(": ")
+	}
+
+	fmt.Fprintf(&b, "%s: %s", d.Severity, d.Message)
+
+	if d.Code != "" {
+		fmt.Fprintf(&b, " [%s]", d.Code)
+	}
+
+	for _, r := range d.Related {
+		fmt.Fprintf(&b, "\n\t%s: %s", r.Position, r.Message)
+	}
+
+	return b.String()
+}
+
+func NewDiagnostics() *Diagnostics {
+	return &Diagnostics{}
+}
+
+// Appends the given diagnostics.
+func (d *Diagnostics) Add(diagnostics ...*Diagnostic) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.items = append(d.items, diagnostics...)
+}
+
+// Reports the given error. If it contains diagnostics, they are appended as is, else
+// it is reported as an internal error without any position.
+func (d *Diagnostics) Report(err error) {
+	if err == nil {
+		return
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			d.Report(e)
+		}
+		return
+	}
+
+	var diag *Diagnostic
+
+	if errors.As(err, &diag) {
+		d.Add(diag)
+		return
+	}
+
+	d.Add(&Diagnostic{
+		Severity: SeverityError,
+		Code:     CodeInternal,
+		Message:  err.Error(),
+	})
+}
+
+// Checks if at least one error diagnostic has been reported.
+func (d *Diagnostics) HasErrors() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, item := range d.items {
+		if item.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Retrieve all diagnostics sorted by position.
+func (d *Diagnostics) Items() []*Diagnostic {
+	d.mu.Lock()
+	items := make([]*Diagnostic, len(d.items))
+	copy(items, d.items)
+	d.mu.Unlock()
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].Position, items[j].Position
+
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+
+		return a.Column < b.Column
+	})
+
+	return items
+}
+
+// Returns ErrFailed if at least one error diagnostic has been reported, nil otherwise.
+func (d *Diagnostics) Err() error {
+	if d.HasErrors() {
+		return ErrFailed
+	}
+
+	return nil
+}
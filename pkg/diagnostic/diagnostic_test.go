@@ -0,0 +1,49 @@
+package diagnostic_test
+
+import (
+	"errors"
+	"go/token"
+	"testing"
+
+	"github.com/YuukanOO/ease/pkg/diagnostic"
+)
+
+func TestDiagnostics(t *testing.T) {
+	t.Run("should report joined errors as diagnostics", func(t *testing.T) {
+		d := diagnostic.NewDiagnostics()
+		pos := token.Position{Filename: "service.go", Line: 2, Column: 1}
+
+		d.Report(errors.Join(
+			diagnostic.Errorf(pos, "some-code", "something went wrong"),
+			errors.New("unexpected"),
+		))
+
+		items := d.Items()
+
+		if len(items) != 2 {
+			t.Fatalf("expected 2 diagnostics, got %d", len(items))
+		}
+
+		if items[0].Code != diagnostic.CodeInternal || items[0].Position.IsValid() {
+			t.Errorf("expected the unpositioned internal error to come first, got %s", items[0])
+		}
+
+		if items[1].Code != "some-code" || items[1].Position != pos {
+			t.Errorf("expected the diagnostic to be kept as is, got %s", items[1])
+		}
+
+		if !d.HasErrors() || !errors.Is(d.Err(), diagnostic.ErrFailed) {
+			t.Error("expected diagnostics to have errors")
+		}
+	})
+
+	t.Run("should not fail on warnings only", func(t *testing.T) {
+		d := diagnostic.NewDiagnostics()
+
+		d.Add(diagnostic.Warnf(token.Position{}, "some-code", "be careful"))
+
+		if d.HasErrors() || d.Err() != nil {
+			t.Error("expected diagnostics to have no errors")
+		}
+	})
+}
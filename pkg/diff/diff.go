@@ -0,0 +1,259 @@
+// Package diff computes line differences between two texts and formats them as unified diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	OpEqual Op = iota
+	OpDelete
+	OpInsert
+)
+
+const noNewline = "\n\\ No newline at end of file\n"
+
+type (
+	Op uint8 // Kind of an edit
+
+	// Single line edit needed to go from the old text to the new one.
+	Edit struct {
+		Op   Op
+		Line string // Line content, including its line feed if any
+	}
+)
+
+// Computes the shortest list of line edits to transform old into new using the Myers
+// algorithm.
+func Lines(old, new string) []Edit {
+	a, b := split(old), split(new)
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	var trace [][]int
+
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset)
+			}
+		}
+	}
+
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []string, offset int) []Edit {
+	var (
+		edits []Edit
+		x, y  = len(a), len(b)
+	)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, Edit{OpEqual, a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, Edit{OpInsert, b[y-1]})
+			} else {
+				edits = append(edits, Edit{OpDelete, a[x-1]})
+			}
+		}
+
+		x, y = prevX, prevY
+	}
+
+	// Edits were built from the end
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+
+	return edits
+}
+
+// Formats differences between old and new as a unified diff with the given number of
+// context lines. Returns an empty string if both texts are the same.
+func Unified(oldName, newName, old, new string, context int) string {
+	edits := Lines(old, new)
+
+	var (
+		b       strings.Builder
+		changed bool
+	)
+
+	for _, edit := range edits {
+		if edit.Op != OpEqual {
+			changed = true
+			break
+		}
+	}
+
+	if !changed {
+		return ""
+	}
+
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	for _, h := range hunks(edits, context) {
+		h.write(&b, edits)
+	}
+
+	return b.String()
+}
+
+type hunk struct {
+	start, end         int // Range of edits covered by the hunk
+	oldStart, newStart int // 1-based line numbers of the first line of the hunk
+	oldCount, newCount int
+}
+
+// Groups edits into hunks surrounded by at most context unchanged lines, hunks closer
+// than twice the context are merged.
+func hunks(edits []Edit, context int) []*hunk {
+	var (
+		result           []*hunk
+		current          *hunk
+		oldLine, newLine = 1, 1
+		oldAt            = make([]int, len(edits)) // Line numbers before each edit
+		newAt            = make([]int, len(edits))
+	)
+
+	for i, edit := range edits {
+		oldAt[i], newAt[i] = oldLine, newLine
+
+		if edit.Op != OpInsert {
+			oldLine++
+		}
+
+		if edit.Op != OpDelete {
+			newLine++
+		}
+	}
+
+	for i, edit := range edits {
+		if edit.Op == OpEqual {
+			continue
+		}
+
+		start := i - context
+
+		if start < 0 {
+			start = 0
+		}
+
+		end := i + context + 1
+
+		if end > len(edits) {
+			end = len(edits)
+		}
+
+		if current != nil && start <= current.end {
+			current.end = end
+			continue
+		}
+
+		current = &hunk{start: start, end: end}
+		result = append(result, current)
+	}
+
+	for _, h := range result {
+		h.oldStart, h.newStart = oldAt[h.start], newAt[h.start]
+
+		for _, edit := range edits[h.start:h.end] {
+			if edit.Op != OpInsert {
+				h.oldCount++
+			}
+
+			if edit.Op != OpDelete {
+				h.newCount++
+			}
+		}
+
+		// An empty range starts at the line before it by convention
+		if h.oldCount == 0 {
+			h.oldStart--
+		}
+
+		if h.newCount == 0 {
+			h.newStart--
+		}
+	}
+
+	return result
+}
+
+func (h *hunk) write(b *strings.Builder, edits []Edit) {
+	fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@\n", h.oldStart, h.oldCount, h.newStart, h.newCount)
+
+	for _, edit := range edits[h.start:h.end] {
+		switch edit.Op {
+		case OpEqual:
+			b.WriteString(" ")
+		case OpDelete:
+			b.WriteString("-")
+		case OpInsert:
+			b.WriteString("+")
+		}
+
+		if strings.HasSuffix(edit.Line, "\n") {
+			b.WriteString(edit.Line)
+		} else {
+			b.WriteString(edit.Line + noNewline)
+		}
+	}
+}
+
+// Split the given text in lines, keeping line feeds.
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(s, "\n")
+
+	// A trailing line feed does not start a new line
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
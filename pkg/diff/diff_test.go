@@ -0,0 +1,53 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/YuukanOO/ease/pkg/diff"
+)
+
+func TestUnified(t *testing.T) {
+	t.Run("should return an empty string for identical texts", func(t *testing.T) {
+		if d := diff.Unified("a", "b", "foo\nbar\n", "foo\nbar\n", 3); d != "" {
+			t.Errorf("expected no diff, got %s", d)
+		}
+	})
+
+	t.Run("should format changes as hunks with context", func(t *testing.T) {
+		old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+		new := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n"
+		expected := `--- old
++++ new
+@@ -2,9 +2,10 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+ 9
+ 10
++11
+`
+
+		if d := diff.Unified("old", "new", old, new, 3); d != expected {
+			t.Errorf("expected\n%s\ngot\n%s", expected, d)
+		}
+	})
+
+	t.Run("should handle added and removed files", func(t *testing.T) {
+		expected := `--- old
++++ new
+@@ -0,0 +1,2 @@
++a
++b
+\ No newline at end of file
+`
+
+		if d := diff.Unified("old", "new", "", "a\nb", 3); d != expected {
+			t.Errorf("expected\n%s\ngot\n%s", expected, d)
+		}
+	})
+}
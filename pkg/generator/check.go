@@ -0,0 +1,117 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/YuukanOO/ease/pkg/diff"
+)
+
+const diffContext = 3
+
+const (
+	ChangeAdded    ChangeKind = iota // File which does not exist yet
+	ChangeModified                   // File whose content is not the generated one
+	ChangeStale                      // File previously generated which is not produced anymore
+)
+
+var ErrOutdated = errors.New("generated files are out of date")
+
+type (
+	ChangeKind uint8
+
+	// Difference between a generated file and the one on disk.
+	Change struct {
+		Dir  string // Output directory of the file
+		Path string // Path relative to the output directory
+		Kind ChangeKind
+		Diff string // Unified diff between the file on disk and the generated one
+	}
+
+	// Returned by a generator in check mode when files on disk are not the generated ones.
+	OutdatedError struct {
+		Changes []*Change
+	}
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeModified:
+		return "modified"
+	default:
+		return "stale"
+	}
+}
+
+func (e *OutdatedError) Error() string {
+	return fmt.Sprintf("%v: %d file(s) differ", ErrOutdated, len(e.Changes))
+}
+
+func (e *OutdatedError) Unwrap() error { return ErrOutdated }
+
+// Compares the generated content of the given file, relative to the given directory, with
+// the one on disk.
+func (c *context) compare(dir, path string, data []byte) error {
+	existing, err := os.ReadFile(filepath.Join(dir, path))
+
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+
+	kind := ChangeModified
+
+	if err != nil {
+		kind = ChangeAdded
+	}
+
+	c.addChange(dir, path, kind, string(existing), string(data))
+
+	return nil
+}
+
+func (c *context) addChange(dir, path string, kind ChangeKind, old, new string) {
+	name := filepath.ToSlash(filepath.Join(displayDir(dir), path))
+	oldName, newName := "a/"+name, "b/"+name
+
+	switch kind {
+	case ChangeAdded:
+		oldName = os.DevNull
+	case ChangeStale:
+		newName = os.DevNull
+	}
+
+	c.changes = append(c.changes, &Change{
+		Dir:  dir,
+		Path: path,
+		Kind: kind,
+		Diff: diff.Unified(oldName, newName, old, new, diffContext),
+	})
+}
+
+// Returns the given directory relative to the working one if possible, for display purposes.
+func displayDir(dir string) string {
+	wd, err := os.Getwd()
+
+	if err != nil {
+		return dir
+	}
+
+	rel, err := filepath.Rel(wd, dir)
+
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return dir
+	}
+
+	return rel
+}
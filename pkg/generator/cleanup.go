@@ -0,0 +1,109 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Generated Go files must start with it, files without it are only removed if they were
+// not modified since they were emitted.
+const GeneratedHeader = "// Code generated by ease"
+
+// File previously emitted which is not anymore.
+type staleFile struct {
+	dir  string // Output directory, relative to the manifest one
+	path string // Path relative to the output directory
+}
+
+// Returns files listed in the given previous outputs which were not emitted this time and
+// still carry the generated header or their emitted content, meaning they were not taken
+// over by someone else.
+func staleFiles(root, relDir string, previous map[string]*Output, current *Manifest) ([]*staleFile, error) {
+	var (
+		stale []*staleFile
+		seen  = make(map[string]bool)
+	)
+
+	for _, output := range previous {
+		// Never go outside the manifest directory, or the current output one, whatever
+		// the manifest says
+		if output.Dir != relDir && !filepath.IsLocal(filepath.FromSlash(output.Dir)) {
+			continue
+		}
+
+		for file, sum := range output.Files {
+			path := output.path(file)
+
+			if seen[path] || current.Has(path) || !filepath.IsLocal(filepath.FromSlash(file)) {
+				continue
+			}
+
+			seen[path] = true
+			generated, err := isGenerated(filepath.Join(root, filepath.FromSlash(path)), sum)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if generated {
+				stale = append(stale, &staleFile{dir: output.Dir, path: file})
+			}
+		}
+	}
+
+	sort.Slice(stale, func(i, j int) bool {
+		return path.Join(stale[i].dir, stale[i].path) < path.Join(stale[j].dir, stale[j].path)
+	})
+
+	return stale, nil
+}
+
+// Removes the given stale files and their parent directories inside their output one if
+// they are now empty. Previous output directories are removed too once empty.
+func removeStale(root, relDir string, stale []*staleFile) error {
+	for _, file := range stale {
+		dir := filepath.Join(root, filepath.FromSlash(file.dir))
+		fullpath := filepath.Join(dir, filepath.FromSlash(file.path))
+
+		if err := os.Remove(fullpath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		for parent := filepath.Dir(fullpath); strings.HasPrefix(parent, dir+string(filepath.Separator)); parent = filepath.Dir(parent) {
+			// Fails if the directory is not empty, which is expected
+			if os.Remove(parent) != nil {
+				break
+			}
+		}
+
+		// Fails if something else remains in the previous output directory
+		if file.dir != relDir {
+			os.Remove(dir)
+		}
+	}
+
+	return nil
+}
+
+// Checks if the file at the given path exists and starts with the generated header or
+// still has the content hashed when it was emitted, which is needed for files which can
+// not carry the header, such as the ones emitted by plugins.
+func isGenerated(path string, sum string) (bool, error) {
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.HasPrefix(data, []byte(GeneratedHeader)) || hash(data) == sum, nil
+}
@@ -6,11 +6,8 @@ import (
 	"go/format"
 	"os"
 	"path/filepath"
-	"strings"
 	"text/template"
 
-	"github.com/YuukanOO/ease/pkg/collection"
-	"github.com/YuukanOO/ease/pkg/crypto"
 	"github.com/YuukanOO/ease/pkg/parser"
 )
 
@@ -32,8 +29,21 @@ type (
 
 		// Template helpers
 
-		Declaration(ScopedDecl) string    // Generates a declaration from a type or a func
-		Identifier(string, string) string // Generates a unique identifier for the second string, the first one is used as a prefix, this is useful to avoid name conflicts
+		Declaration(ScopedDecl) string            // Generates a declaration from a type or a func
+		Expr(*parser.TypeExpr) string             // Generates a type expression such as map[string][]*pkg.Todo
+		VarType(*parser.Var) string               // Generates the type of a var, ...T for variadic params
+		Zero(*parser.TypeExpr) string             // Generates the zero value of a type expression, such as nil, 0 or *new(pkg.Todo)
+		Identifier(string, string) string         // Generates a unique identifier for the second string, the first one is used as a prefix, this is useful to avoid name conflicts
+		ReadableIdentifier(string, string) string // Same as Identifier for fields and methods, the prefix in camelCase is used as is when no other key uses it, else suffixed by the package of the key or a counter
+		Import(string) string                     // Imports the package at the given path in the emitted Go file and returns the name to reference it with, assumed from the path
+		Imports(...any) []*parser.Package         // Packages referenced by the given declarations, vars and type expressions, sorted by path
+		Camel(string) string                      // Converts an identifier to camelCase
+		Pascal(string) string                     // Converts an identifier to PascalCase
+		Snake(string) string                      // Converts an identifier to snake_case
+		Kebab(string) string                      // Converts an identifier to kebab-case
+		Quote(string) string                      // Generates a Go string literal
+		Comment(string) string                    // Generates a // comment from a possibly multiline text
+		JSONName(*parser.Field) string            // Retrieve the JSON name of a struct field from its tag and its name, empty if the field is ignored
 
 		// Generation helpers
 
@@ -44,23 +54,34 @@ type (
 	context struct {
 		parser.Result
 
-		identifiers *collection.Set[string]
+		identifiers *identifiers
+		imports     *fileImports // Packages imported by the template being emitted, nil otherwise
 		dir         string
+		relDir      string // Output directory relative to the manifest one
+		manifest    *Manifest
+		generator   string    // Name of the extension currently generating files
+		check       bool      // Compare emitted files with the ones on disk instead of writing them
+		changes     []*Change // Differences found in check mode
 	}
 )
 
-func newContext(dir string, result parser.Result) Context {
+func newContext(dir, relDir string, result parser.Result, check bool) *context {
 	return &context{
 		dir:         dir,
-		identifiers: collection.NewSet[string](),
+		relDir:      relDir,
+		identifiers: newIdentifiers(),
+		manifest:    newManifest(),
+		check:       check,
 		Result:      result,
 	}
 }
 
 func (c *context) Identifier(prefix string, key string) string {
-	return c.identifiers.SetFunc(key, func() string {
-		return fmt.Sprintf("%s_%s", strings.ReplaceAll(prefix, "-", "_"), crypto.Prefix(key, identifierPrefixLength))
-	})
+	return c.identifiers.get(prefix, key, false)
+}
+
+func (c *context) ReadableIdentifier(prefix string, key string) string {
+	return c.identifiers.get(prefix, key, true)
 }
 
 func (c *context) Declaration(decl ScopedDecl) string {
@@ -69,36 +90,91 @@ func (c *context) Declaration(decl ScopedDecl) string {
 	}
 
 	return fmt.Sprintf("%s.%s",
-		c.Identifier(decl.Package().Name(), decl.Package().Path()),
+		c.importPackage(decl.Package().Path(), decl.Package().DeclaredName()),
 		decl.Name(),
 	)
 }
 
+func (c *context) Import(path string) string {
+	return c.importPackage(path, "")
+}
+
+// Registers the package in the imports of the emitted template. Outside of templates,
+// imports can not be managed so a unique alias is returned.
+func (c *context) importPackage(path, name string) string {
+	if c.imports != nil {
+		return c.imports.add(path, name)
+	}
+
+	if name == "" {
+		name = assumedName(path)
+	}
+
+	return c.Identifier(name, path)
+}
+
+func (c *context) Expr(expr *parser.TypeExpr) string {
+	return expr.Render(func(t *parser.Type) string { return c.Declaration(t) })
+}
+
+// Templates are cloned so helpers given by Funcs can be bound to this context without
+// modifying the given template. Imports of Go files are managed so templates only have
+// to reference packages through Declaration, Expr or Import, unused imports written in
+// the template being removed.
 func (c *context) EmitTemplate(path string, tmpl *template.Template, data any) error {
+	bound, err := tmpl.Clone()
+
+	if err != nil {
+		return err
+	}
+
 	var buf bytes.Buffer
 
-	if err := tmpl.Execute(&buf, data); err != nil {
+	c.imports = newFileImports()
+	defer func() { c.imports = nil }()
+
+	if err = bound.Funcs(Funcs(c)).Execute(&buf, data); err != nil {
 		return err
 	}
 
-	return c.EmitFile(path, buf.Bytes())
+	if filepath.Ext(path) != ".go" {
+		return c.EmitFile(path, c.imports.replace(buf.Bytes(), func(pkg *importedPackage) string { return pkg.name }))
+	}
+
+	return c.EmitFile(path, c.imports.resolve(buf.Bytes(), c.Identifier))
 }
 
 func (c *context) EmitFile(path string, data []byte) error {
-	p, err := c.mkdirAll(path)
+	// Format Go source files, other ones are emitted as is
+	if filepath.Ext(path) == ".go" {
+		formatted, err := format.Source(data)
 
-	if err != nil {
-		return err
+		if err != nil {
+			return err
+		}
+
+		data = formatted
 	}
 
-	// Format the source file
-	data, err = format.Source(data)
+	c.manifest.add(c.generator, c.relDir, path, data)
+
+	return c.output(path, data)
+}
+
+// Writes the given file relative to the output dir, or compare it with the one on disk
+// in check mode.
+func (c *context) output(path string, data []byte) error {
+	if c.check {
+		return c.compare(c.dir, path, data)
+	}
+
+	p, err := c.mkdirAll(path)
 
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(p, data, defaultPermissions)
+	return writeFile(p, data)
 }
 
 // Creates all directories and resolve the given path before returning it.
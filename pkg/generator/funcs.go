@@ -0,0 +1,190 @@
+package generator
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/YuukanOO/ease/pkg/naming"
+	"github.com/YuukanOO/ease/pkg/parser"
+)
+
+// Funcs returns helpers of the given context as template functions, so they may be used
+// as {{ expr .TypeExpr }} instead of {{ $.Expr .TypeExpr }} in templates which data does
+// not embed the context.
+//
+// Templates must know functions before being parsed, a nil context may be given for this
+// purpose since EmitTemplate binds them to the generating context anyway.
+func Funcs(ctx Context) template.FuncMap {
+	return template.FuncMap{
+		"declaration":        func(decl ScopedDecl) string { return ctx.Declaration(decl) },
+		"expr":               func(expr *parser.TypeExpr) string { return ctx.Expr(expr) },
+		"varType":            func(v *parser.Var) string { return ctx.VarType(v) },
+		"zero":               func(expr *parser.TypeExpr) string { return ctx.Zero(expr) },
+		"identifier":         func(prefix, key string) string { return ctx.Identifier(prefix, key) },
+		"readableIdentifier": func(prefix, key string) string { return ctx.ReadableIdentifier(prefix, key) },
+		"import":             func(path string) string { return ctx.Import(path) },
+		"imports":            func(values ...any) []*parser.Package { return ctx.Imports(values...) },
+		"camel":              naming.Camel,
+		"pascal":             naming.Pascal,
+		"snake":              naming.Snake,
+		"kebab":              naming.Kebab,
+		"quote":              strconv.Quote,
+		"comment":            Comment,
+		"jsonName":           JSONName,
+	}
+}
+
+// Comment turns the given text into a // comment, one per line. Returns an empty string
+// for an empty text so it may be used for optional doc comments.
+func Comment(text string) string {
+	text = strings.TrimSpace(text)
+
+	if text == "" {
+		return ""
+	}
+
+	lines := strings.Split(text, "\n")
+
+	for i, line := range lines {
+		if line = strings.TrimRight(line, " \t\r"); line == "" {
+			lines[i] = "//"
+		} else {
+			lines[i] = "// " + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// JSONName returns the name of a struct field once encoded by encoding/json from its tag
+// and its name. Returns an empty string if the field is ignored, unexported or embedded
+// without a name in its tag since its fields are promoted.
+func JSONName(field *parser.Field) string {
+	value := reflect.StructTag(field.Tag()).Get("json")
+	name, _, _ := strings.Cut(value, ",")
+
+	switch {
+	case value == "-": // While json:"-," names the field -
+		return ""
+	case name != "":
+		return name
+	case field.IsEmbedded() || !field.IsExported():
+		return ""
+	default:
+		return field.Name()
+	}
+}
+
+func (c *context) VarType(v *parser.Var) string { return c.Expr(v.TypeExpr()) }
+
+func (c *context) Zero(expr *parser.TypeExpr) string {
+	switch expr.Kind() {
+	case parser.ExprKindPointer, parser.ExprKindSlice, parser.ExprKindMap, parser.ExprKindChan,
+		parser.ExprKindFunc, parser.ExprKindInterface:
+		return "nil"
+	case parser.ExprKindArray, parser.ExprKindStruct:
+		return c.Expr(expr) + "{}"
+	case parser.ExprKindNamed:
+		if zero, found := builtinZeros[expr.Type().String()]; found {
+			return zero
+		}
+	}
+
+	// The underlying type of named types is unknown, this works for every one of them
+	return "*new(" + c.Expr(expr) + ")"
+}
+
+func (c *context) Imports(values ...any) []*parser.Package {
+	pkgs := make(map[string]*parser.Package)
+
+	for _, value := range values {
+		collectPackages(pkgs, reflect.ValueOf(value))
+	}
+
+	result := make([]*parser.Package, 0, len(pkgs))
+
+	for _, pkg := range pkgs {
+		result = append(result, pkg)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Path() < result[j].Path() })
+
+	return result
+}
+
+func (c *context) Camel(s string) string               { return naming.Camel(s) }
+func (c *context) Pascal(s string) string              { return naming.Pascal(s) }
+func (c *context) Snake(s string) string               { return naming.Snake(s) }
+func (c *context) Kebab(s string) string               { return naming.Kebab(s) }
+func (c *context) Quote(s string) string               { return strconv.Quote(s) }
+func (c *context) Comment(text string) string          { return Comment(text) }
+func (c *context) JSONName(field *parser.Field) string { return JSONName(field) }
+
+// Zero values of builtin named types, other ones use the generic *new(T) form.
+var builtinZeros = map[string]string{
+	"bool": "false", "string": `""`, "error": "nil", "any": "nil",
+	"int": "0", "int8": "0", "int16": "0", "int32": "0", "int64": "0",
+	"uint": "0", "uint8": "0", "uint16": "0", "uint32": "0", "uint64": "0", "uintptr": "0",
+	"float32": "0", "float64": "0", "complex64": "0", "complex128": "0", "byte": "0", "rune": "0",
+}
+
+// Adds packages referenced by the given value to pkgs, slices are walked so results of
+// other helpers such as .Params may be given as is.
+func collectPackages(pkgs map[string]*parser.Package, value reflect.Value) {
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+
+	if !value.IsValid() || (value.Kind() == reflect.Pointer && value.IsNil()) {
+		return
+	}
+
+	if value.Kind() == reflect.Slice {
+		for i := 0; i < value.Len(); i++ {
+			collectPackages(pkgs, value.Index(i))
+		}
+
+		return
+	}
+
+	add := func(pkg *parser.Package) {
+		if pkg != nil {
+			pkgs[pkg.Path()] = pkg
+		}
+	}
+
+	addExpr := func(expr *parser.TypeExpr) {
+		for _, pkg := range expr.Packages() {
+			add(pkg)
+		}
+	}
+
+	switch v := value.Interface().(type) {
+	case *parser.TypeExpr:
+		addExpr(v)
+	case *parser.Var:
+		addExpr(v.TypeExpr())
+	case *parser.Field:
+		addExpr(v.TypeExpr())
+	case *parser.Package:
+		add(v)
+	case ScopedDecl:
+		add(v.Package())
+
+		// Funcs, and instantiated dependencies, need the types of their signature
+		if fn, ok := v.(interface {
+			Params() parser.Vars
+			Returns() parser.Vars
+		}); ok {
+			collectPackages(pkgs, reflect.ValueOf(fn.Params()))
+			collectPackages(pkgs, reflect.ValueOf(fn.Returns()))
+		}
+
+		if dep, ok := v.(*parser.Dependency); ok {
+			collectPackages(pkgs, reflect.ValueOf(dep.TypeArgs()))
+		}
+	}
+}
@@ -1,6 +1,15 @@
 package generator
 
-import "github.com/YuukanOO/ease/pkg/parser"
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/YuukanOO/ease/pkg/parser"
+)
 
 type (
 	Generator interface {
@@ -12,28 +21,221 @@ type (
 		Generate(Context) error
 	}
 
+	// Extensions may implement it to give a stable name to the files they emit in the
+	// manifest, the Go type name is used otherwise.
+	NamedExtension interface {
+		Extension
+		Name() string
+	}
+
+	// Configuration of a generator, only the output directory is required.
+	Config struct {
+		Dir         string // Output directory
+		ManifestDir string // Directory of the manifest, the output one by default
+		Check       bool   // Compare files with the ones on disk instead of writing them
+	}
+
 	generator struct {
-		dir        string
+		Config
 		extensions []Extension
 	}
 )
 
 // Builds a new generator with output dir and given extensions.
 func New(dir string, extensions ...Extension) Generator {
+	return NewWithConfig(Config{Dir: dir}, extensions...)
+}
+
+// Builds a generator which does not write anything but returns an *OutdatedError if
+// files in the output dir are not the ones which would be generated.
+func NewCheck(dir string, extensions ...Extension) Generator {
+	return NewWithConfig(Config{Dir: dir, Check: true}, extensions...)
+}
+
+// Builds a new generator with the given configuration and extensions. Generators of the
+// same project should share their manifest directory: when an extension writes to
+// another directory, files it emitted in the previous one are removed too.
+func NewWithConfig(cfg Config, extensions ...Extension) Generator {
+	if cfg.ManifestDir == "" {
+		cfg.ManifestDir = cfg.Dir
+	}
+
 	return &generator{
-		dir:        dir,
+		Config:     cfg,
 		extensions: extensions,
 	}
 }
 
 func (g *generator) Generate(result parser.Result) error {
-	ctx := newContext(g.dir, result)
+	relDir, err := filepath.Rel(g.ManifestDir, g.Dir)
+
+	if err != nil {
+		return err
+	}
+
+	relDir = filepath.ToSlash(relDir)
+	previous, legacy, err := g.readManifest(relDir)
+
+	if err != nil {
+		return err
+	}
+
+	var (
+		ctx     = newContext(g.Dir, relDir, result, g.Check)
+		running = make(map[string]bool, len(g.extensions))
+	)
 
 	for _, extension := range g.extensions {
+		ctx.generator = extensionName(extension)
+		running[ctx.generator] = true
+
 		if err := extension.Generate(ctx); err != nil {
 			return err
 		}
 	}
 
+	// Errors may have been reported instead of returned, since the output is incomplete,
+	// do not consider missing files as stale ones
+	if result.Diagnostics().HasErrors() {
+		return nil
+	}
+
+	// Outputs of running extensions and the ones which wrote to this directory are
+	// replaced, other extensions are run by generators of other directories
+	replaced := make(map[string]*Output)
+
+	for name, output := range previous.Outputs {
+		if running[name] || output.Dir == relDir {
+			replaced[name] = output
+		} else {
+			ctx.manifest.Outputs[name] = output
+		}
+	}
+
+	if err = g.outputManifest(ctx, previous, legacy); err != nil {
+		return err
+	}
+
+	stale, err := staleFiles(g.ManifestDir, relDir, replaced, ctx.manifest)
+
+	if err != nil {
+		return err
+	}
+
+	if !g.Check {
+		return removeStale(g.ManifestDir, relDir, stale)
+	}
+
+	for _, file := range stale {
+		dir := filepath.Join(g.ManifestDir, filepath.FromSlash(file.dir))
+		existing, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file.path)))
+
+		if err != nil {
+			return err
+		}
+
+		ctx.addChange(dir, file.path, ChangeStale, string(existing), "")
+	}
+
+	if len(ctx.changes) > 0 {
+		return &OutdatedError{Changes: ctx.changes}
+	}
+
 	return nil
 }
+
+// Reads the manifest and, when it is not stored in the output directory, migrates the
+// one previous versions wrote there, returning true if there was one.
+func (g *generator) readManifest(relDir string) (*Manifest, bool, error) {
+	manifest, err := ReadManifest(g.ManifestDir)
+
+	if err != nil || relDir == "." {
+		return manifest, false, err
+	}
+
+	legacy, err := ReadManifest(g.Dir)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	for name, output := range legacy.Outputs {
+		if _, found := manifest.Outputs[name]; !found {
+			output.Dir = path.Join(relDir, output.Dir)
+			manifest.Outputs[name] = output
+		}
+	}
+
+	return manifest, !legacy.IsEmpty(), nil
+}
+
+// Writes the manifest of emitted files. When nothing has been emitted, the previous one
+// is removed since there is nothing left to track, as is the one migrated from the
+// output directory.
+func (g *generator) outputManifest(ctx *context, previous *Manifest, legacy bool) error {
+	if legacy {
+		if err := g.removeManifest(ctx, g.Dir); err != nil {
+			return err
+		}
+	}
+
+	if ctx.manifest.IsEmpty() {
+		if previous.IsEmpty() {
+			return nil
+		}
+
+		return g.removeManifest(ctx, g.ManifestDir)
+	}
+
+	data, err := ctx.manifest.encode()
+
+	if err != nil {
+		return err
+	}
+
+	if g.Check {
+		return ctx.compare(g.ManifestDir, ManifestFilename, data)
+	}
+
+	if err = os.MkdirAll(g.ManifestDir, defaultDirPermissions); err != nil {
+		return err
+	}
+
+	return writeFile(filepath.Join(g.ManifestDir, ManifestFilename), data)
+}
+
+// Removes the manifest of the given directory, or reports it as stale in check mode.
+func (g *generator) removeManifest(ctx *context, dir string) error {
+	path := filepath.Join(dir, ManifestFilename)
+
+	if !g.Check {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		return nil
+	}
+
+	existing, err := os.ReadFile(path)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	ctx.addChange(dir, ManifestFilename, ChangeStale, string(existing), "")
+
+	return nil
+}
+
+// Retrieve the name under which files emitted by the given extension are recorded.
+func extensionName(extension Extension) string {
+	if named, ok := extension.(NamedExtension); ok {
+		return named.Name()
+	}
+
+	return fmt.Sprintf("%T", extension)
+}
@@ -0,0 +1,549 @@
+package generator_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/YuukanOO/ease/pkg/diagnostic"
+	"github.com/YuukanOO/ease/pkg/generator"
+	"github.com/YuukanOO/ease/pkg/parser"
+)
+
+type (
+	fakeResult struct {
+		parser.Result
+		diagnostics *diagnostic.Diagnostics
+	}
+
+	// Emits a file for every path, only Go files carry the generated header.
+	fakeExtension []string
+
+	funcExtension func(generator.Context) error
+)
+
+func (r *fakeResult) Diagnostics() *diagnostic.Diagnostics { return r.diagnostics }
+
+func (e fakeExtension) Name() string { return "fake" }
+
+func (e fakeExtension) Generate(ctx generator.Context) error {
+	for _, path := range e {
+		content := "generated\n"
+
+		if filepath.Ext(path) == ".go" {
+			content = generator.GeneratedHeader + "; DO NOT EDIT\npackage generated\n"
+		}
+
+		if err := ctx.EmitFile(path, []byte(content)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e funcExtension) Generate(ctx generator.Context) error { return e(ctx) }
+
+func generate(gen generator.Generator) error {
+	return gen.Generate(&fakeResult{diagnostics: diagnostic.NewDiagnostics()})
+}
+
+func TestStaleFiles(t *testing.T) {
+	t.Run("should remove stale generated files only", func(t *testing.T) {
+		dir := t.TempDir()
+
+		if err := generate(generator.New(dir, fakeExtension{"a.go", "b.go", "sub/c.go"})); err != nil {
+			t.Fatal(err)
+		}
+
+		// b.go has been taken over by the user
+		if err := os.WriteFile(filepath.Join(dir, "b.go"), []byte("package generated\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := generate(generator.New(dir, fakeExtension{"a.go"})); err != nil {
+			t.Fatal(err)
+		}
+
+		for path, expected := range map[string]bool{
+			"a.go":     true,
+			"b.go":     true,
+			"sub/c.go": false,
+			"sub":      false,
+		} {
+			if _, err := os.Stat(filepath.Join(dir, path)); (err == nil) != expected {
+				t.Errorf("expected %s to exist: %t, got error %v", path, expected, err)
+			}
+		}
+
+		manifest, err := generator.ReadManifest(dir)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if manifest.Has("sub/c.go") || !manifest.Has("a.go") {
+			t.Errorf("expected the manifest to only contain a.go, got %v", manifest.Outputs)
+		}
+	})
+
+	t.Run("should remove stale files without header only if they were not modified", func(t *testing.T) {
+		dir := t.TempDir()
+
+		if err := generate(generator.New(dir, fakeExtension{"a.txt", "b.txt"})); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("edited\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := generate(generator.New(dir)); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "a.txt")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected a.txt to be removed, got %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "b.txt")); err != nil {
+			t.Errorf("expected the modified b.txt to be kept, got %v", err)
+		}
+	})
+
+	t.Run("should report stale files in check mode without removing them", func(t *testing.T) {
+		dir := t.TempDir()
+
+		if err := generate(generator.New(dir, fakeExtension{"a.go", "b.go"})); err != nil {
+			t.Fatal(err)
+		}
+
+		err := generate(generator.NewCheck(dir, fakeExtension{"a.go"}))
+
+		var outdated *generator.OutdatedError
+
+		if !errors.As(err, &outdated) {
+			t.Fatalf("expected an outdated error, got %v", err)
+		}
+
+		// The manifest is outdated too
+		if len(outdated.Changes) != 2 || outdated.Changes[1].Path != "b.go" || outdated.Changes[1].Kind != generator.ChangeStale {
+			t.Errorf("expected b.go to be stale, got %v", outdated.Changes)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "b.go")); err != nil {
+			t.Errorf("expected b.go to still exist, got %v", err)
+		}
+	})
+}
+
+type otherExtension struct{ fakeExtension }
+
+func (e otherExtension) Name() string { return "other" }
+
+func TestSharedManifest(t *testing.T) {
+	exists := func(t *testing.T, root string, expected map[string]bool) {
+		t.Helper()
+
+		for path, exists := range expected {
+			if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(path))); (err == nil) != exists {
+				t.Errorf("expected %s to exist: %t, got error %v", path, exists, err)
+			}
+		}
+	}
+
+	t.Run("should remove files of the previous output directory of a generator", func(t *testing.T) {
+		root := t.TempDir()
+		run := func(gen, other string) {
+			for dir, extension := range map[string]generator.Extension{
+				gen:   fakeExtension{"a.go", "sub/b.go"},
+				other: otherExtension{fakeExtension{"c.go"}},
+			} {
+				err := generate(generator.NewWithConfig(generator.Config{Dir: filepath.Join(root, dir), ManifestDir: root}, extension))
+
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		run("generated", "other")
+		run("moved", "other")
+
+		exists(t, root, map[string]bool{
+			"moved/a.go":                          true,
+			"moved/sub/b.go":                      true,
+			"other/c.go":                          true,
+			"generated":                           false,
+			"other/" + generator.ManifestFilename: false,
+		})
+
+		manifest, err := generator.ReadManifest(root)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !manifest.Has("moved/sub/b.go") || !manifest.Has("other/c.go") || manifest.Has("generated/a.go") {
+			t.Errorf("expected the manifest to record the new output directory, got %v", manifest.Outputs)
+		}
+
+		err = generate(generator.NewWithConfig(generator.Config{Dir: filepath.Join(root, "generated"), ManifestDir: root, Check: true},
+			fakeExtension{"a.go", "sub/b.go"}))
+
+		var outdated *generator.OutdatedError
+
+		if !errors.As(err, &outdated) || len(outdated.Changes) != 5 || outdated.Changes[4].Kind != generator.ChangeStale ||
+			outdated.Changes[4].Path != "sub/b.go" || outdated.Changes[4].Dir != filepath.Join(root, "moved") {
+			t.Errorf("expected moving back to be reported in check mode, got %v", err)
+		}
+	})
+
+	t.Run("should migrate the manifest of the output directory", func(t *testing.T) {
+		root := t.TempDir()
+		dir := filepath.Join(root, "generated")
+
+		if err := generate(generator.New(dir, fakeExtension{"a.go", "b.go"})); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := generate(generator.NewWithConfig(generator.Config{Dir: dir, ManifestDir: root}, fakeExtension{"a.go"})); err != nil {
+			t.Fatal(err)
+		}
+
+		exists(t, root, map[string]bool{
+			"generated/a.go": true,
+			"generated/b.go": false,
+			"generated/" + generator.ManifestFilename: false,
+			generator.ManifestFilename:                true,
+		})
+	})
+}
+
+func TestOverrideTemplates(t *testing.T) {
+	builtin := template.Must(template.New("file").Funcs(template.FuncMap{"upper": strings.ToUpper}).
+		Parse(`{{ block "greeting" . }}Hello{{ end }}, {{ block "name" . }}{{ . }}{{ end }}!`))
+
+	override := func(t *testing.T, files map[string]string) (string, error) {
+		dir := t.TempDir()
+
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		tmpl, err := generator.OverrideTemplates(builtin, dir)
+
+		if err != nil {
+			return "", err
+		}
+
+		var b strings.Builder
+
+		if err = tmpl.Execute(&b, "world"); err != nil {
+			t.Fatal(err)
+		}
+
+		return b.String(), nil
+	}
+
+	t.Run("should override blocks with built-in functions available", func(t *testing.T) {
+		result, err := override(t, map[string]string{
+			"name.tmpl":  `{{ define "name" }}{{ upper . }}{{ end }}`,
+			"ignored.go": `{{ define "unknown" }}{{ end }}`,
+		})
+
+		if err != nil || result != "Hello, WORLD!" {
+			t.Errorf("expected the name block to be overridden, got %q, %v", result, err)
+		}
+
+		// The built-in templates must not have been modified
+		var b strings.Builder
+
+		if err = builtin.Execute(&b, "world"); err != nil || b.String() != "Hello, world!" {
+			t.Errorf("expected built-in templates to be kept, got %q, %v", b.String(), err)
+		}
+	})
+
+	t.Run("should override a whole template", func(t *testing.T) {
+		result, err := override(t, map[string]string{"file.tmpl": `{{ template "greeting" . }} {{ . }}`})
+
+		if err != nil || result != "Hello world" {
+			t.Errorf("expected the whole template to be overridden, got %q, %v", result, err)
+		}
+	})
+
+	for name, content := range map[string]string{
+		"unknown blocks":                  `{{ define "farewell" }}Bye{{ end }}`,
+		"unknown templates":               `Bye`,
+		"undefined functions":             `{{ define "name" }}{{ lower . }}{{ end }}`,
+		"references to missing templates": `{{ define "name" }}{{ template "farewell" . }}{{ end }}`,
+	} {
+		content := content
+
+		t.Run("should reject "+name, func(t *testing.T) {
+			if _, err := override(t, map[string]string{"other.tmpl": content}); !errors.Is(err, generator.ErrInvalidTemplate) {
+				t.Errorf("expected ErrInvalidTemplate, got %v", err)
+			}
+		})
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	result, err := parser.New().Parse("github.com/YuukanOO/ease/pkg/parser/testdata")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	params := make(map[string]*parser.Var)
+	funcs := make(map[string]*parser.Func)
+
+	for _, fn := range result.Funcs() {
+		funcs[fn.Name()] = fn
+
+		for _, param := range fn.Params() {
+			params[param.Name()] = param
+		}
+	}
+
+	dir := t.TempDir()
+	tmpl := template.Must(template.New("").Funcs(generator.Funcs(nil)).
+		Parse(`{{ snake "userID" }} {{ kebab "HTTPServer" }} {{ quote "a\"b" }} {{ zero .TypeExpr }}`))
+
+	err = generator.New(dir, funcExtension(func(ctx generator.Context) error {
+		for name, expected := range map[string]string{
+			"fn":     "nil",
+			"ch":     "nil",
+			"arr":    "[4]byte{}",
+			"anon":   "struct{Name string}{}",
+			"from":   "0",
+			"opts":   "nil",
+			"matrix": "nil",
+		} {
+			if zero := ctx.Zero(params[name].TypeExpr()); zero != expected {
+				t.Errorf("expected the zero value of %s to be %s, got %s", name, expected, zero)
+			}
+		}
+
+		if zero := ctx.Zero(params["ctx"].TypeExpr()); !strings.HasPrefix(zero, "*new(context_") {
+			t.Errorf("expected named types to be zeroed with new, got %s", zero)
+		}
+
+		if typ := ctx.VarType(params["opts"]); typ != "...string" {
+			t.Errorf("expected variadic params to be rendered as such, got %s", typ)
+		}
+
+		if typ := ctx.VarType(params["index"]); !strings.HasPrefix(typ, "map[string][]*testdata_") {
+			t.Errorf("expected the full type of the var, got %s", typ)
+		}
+
+		var paths []string
+
+		for _, pkg := range ctx.Imports(funcs["GetByID"], []*parser.Var{params["fn"]}, nil) {
+			paths = append(paths, pkg.Path())
+		}
+
+		if strings.Join(paths, ",") != "context,github.com/YuukanOO/ease/pkg/parser/testdata" {
+			t.Errorf("expected packages of the func and its signature, got %v", paths)
+		}
+
+		return ctx.EmitTemplate("helpers.txt", tmpl, params["matrix"])
+	})).Generate(result)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if content, err := os.ReadFile(filepath.Join(dir, "helpers.txt")); err != nil || string(content) != `user_id http-server "a\"b" nil` {
+		t.Errorf("expected template functions to be bound to the context, got %q, %v", content, err)
+	}
+
+	if comment := generator.Comment("Does things.\n\n  With details  \n"); comment != "// Does things.\n//\n//   With details" {
+		t.Errorf("expected a comment per line, got %q", comment)
+	}
+
+	expected := map[string]string{
+		"TestModel": "",
+		"ID":        "id",
+		"Title":     "Title",
+		"Secret":    "",
+		"Dash":      "-",
+		"Lat":       "Lat",
+		"Lng":       "Lng",
+		"internal":  "",
+	}
+
+	for _, typ := range result.Types() {
+		if typ.Name() != "TestTaggedModel" {
+			continue
+		}
+
+		if len(typ.Fields()) != len(expected) {
+			t.Fatalf("expected %d fields, got %d", len(expected), len(typ.Fields()))
+		}
+
+		for _, field := range typ.Fields() {
+			if name := generator.JSONName(field); name != expected[field.Name()] {
+				t.Errorf("expected the JSON name of %s to be %q, got %q", field.Name(), expected[field.Name()], name)
+			}
+		}
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	identify := func(t *testing.T, fn func(generator.Context)) {
+		err := generator.New(t.TempDir(), funcExtension(func(ctx generator.Context) error {
+			fn(ctx)
+			return nil
+		})).Generate(&fakeResult{diagnostics: diagnostic.NewDiagnostics()})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	t.Run("should extend the hash when two keys collide", func(t *testing.T) {
+		// Finds two keys which hashes share the same 6 first characters
+		var first, second string
+		seen := make(map[string]string)
+
+		for i := 0; second == ""; i++ {
+			key := fmt.Sprint(i)
+			sum := sha256.Sum256([]byte(key))
+			hash := hex.EncodeToString(sum[:])[:6]
+
+			if existing, found := seen[hash]; found {
+				first, second = existing, key
+			}
+
+			seen[hash] = key
+		}
+
+		identify(t, func(ctx generator.Context) {
+			a, b := ctx.Identifier("value", first), ctx.Identifier("value", second)
+
+			if a == b || len(a) != len("value_")+6 || len(b) != len("value_")+8 {
+				t.Errorf("expected the second identifier to use a longer hash, got %s and %s", a, b)
+			}
+
+			if again := ctx.Identifier("value", second); again != b {
+				t.Errorf("expected the same identifier for the same key, got %s and %s", b, again)
+			}
+		})
+	})
+
+	t.Run("should allocate an identifier per prefix and key", func(t *testing.T) {
+		identify(t, func(ctx generator.Context) {
+			if a, b := ctx.Identifier("a", "key"), ctx.Identifier("b", "key"); !strings.HasPrefix(a, "a_") || !strings.HasPrefix(b, "b_") {
+				t.Errorf("expected each prefix to be used, got %s and %s", a, b)
+			}
+
+			if id := ctx.Identifier("my-pkg.v2", "key"); !strings.HasPrefix(id, "my_pkg_v2_") {
+				t.Errorf("expected the prefix to be sanitized, got %s", id)
+			}
+		})
+	})
+
+	t.Run("should use readable identifiers when available", func(t *testing.T) {
+		identify(t, func(ctx generator.Context) {
+			if id := ctx.ReadableIdentifier("TodoService", "todo.TodoService"); id != "todoService" {
+				t.Errorf("expected todoService, got %s", id)
+			}
+
+			// Identifiers depend on the order they are requested in
+			for _, test := range [][3]string{
+				{"TodoService", "example.com/other.TodoService", "todoServiceOther"},
+				{"TodoService", "example.com/other.TodoService GET /todos", "todoService2"},
+				{"TodoService", "example.com/other.(*TodoService).Update PUT /todos", "todoService3"},
+				{"string", "string", "string"},
+				{"len", "len", "len"},
+				{"New", "example.com/todo.New", "new"},
+				{"type", "type", "type2"},
+			} {
+				if id := ctx.ReadableIdentifier(test[0], test[1]); id != test[2] {
+					t.Errorf("expected %s for %s, got %s", test[2], test[1], id)
+				}
+			}
+
+			if id := ctx.ReadableIdentifier("2fa", "2fa"); !strings.HasPrefix(id, "_2fa_") {
+				t.Errorf("expected prefixes which can not start an identifier to be hashed, got %s", id)
+			}
+
+			if id := ctx.Identifier("len", "example.com/todo.Len"); !strings.HasPrefix(id, "len_") {
+				t.Errorf("expected local identifiers to be hashed, got %s", id)
+			}
+		})
+	})
+}
+
+func TestImports(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := template.Must(template.New("").Funcs(generator.Funcs(nil)).Parse(`package generated
+
+import (
+	"fmt"
+	"os"
+)
+
+var yaml = 1
+
+var (
+	_ = fmt.Sprint
+	_ {{ import "net/http" }}.Handler
+	_ {{ import "fmt" }}.Stringer
+	_ {{ import "gopkg.in/yaml.v3" }}.Node
+	_ {{ import "example.com/other/yaml" }}.Node
+	_ {{ import "example.com/go-todo" }}.Todo
+)
+`))
+
+	err := generator.New(dir, funcExtension(func(ctx generator.Context) error {
+		return ctx.EmitTemplate("imports.go", tmpl, nil)
+	})).Generate(&fakeResult{diagnostics: diagnostic.NewDiagnostics()})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "imports.go"))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Unused imports are removed, conflicting names aliased and standard packages grouped first
+	expected := `package generated
+
+import (
+	"fmt"
+	"net/http"
+
+	"example.com/go-todo"
+	yaml_cc7c8a "example.com/other/yaml"
+	yaml_985259 "gopkg.in/yaml.v3"
+)
+
+var yaml = 1
+
+var (
+	_ = fmt.Sprint
+	_ http.Handler
+	_ fmt.Stringer
+	_ yaml_985259.Node
+	_ yaml_cc7c8a.Node
+	_ todo.Todo
+)
+`
+
+	if string(content) != expected {
+		t.Errorf("expected imports to be managed, got:\n%s", content)
+	}
+}
@@ -0,0 +1,111 @@
+package gin
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/YuukanOO/ease/pkg/diagnostic"
+	"github.com/YuukanOO/ease/pkg/parser/api"
+)
+
+const codeRouteConflict = "route-conflict"
+
+// Node of a routing tree mimicking the gin one to detect conflicts before gin panics at startup.
+type routeNode struct {
+	static   map[string]*routeNode
+	wildcard *routeNode
+	name     string        // Wildcard segment (:name or *name) if this is a wildcard node
+	owner    *api.Endpoint // Endpoint which has introduced this node
+}
+
+func newRouteNode(name string, owner *api.Endpoint) *routeNode {
+	return &routeNode{
+		static: make(map[string]*routeNode),
+		name:   name,
+		owner:  owner,
+	}
+}
+
+// Checks that given endpoints could be registered by gin, that is wildcards at the same
+// position must share the same name and catch-all wildcards can not have siblings.
+// Duplicate routes are already reported by the api parser.
+func checkRoutes(endpoints []*api.Endpoint) []*diagnostic.Diagnostic {
+	var (
+		diagnostics []*diagnostic.Diagnostic
+		trees       = make(map[api.Method]*routeNode)
+	)
+
+	for _, endpoint := range endpoints {
+		tree, found := trees[endpoint.Method()]
+
+		if !found {
+			tree = newRouteNode("", endpoint)
+			trees[endpoint.Method()] = tree
+		}
+
+		if d := tree.insert(endpoint); d != nil {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+
+	return diagnostics
+}
+
+func (n *routeNode) insert(endpoint *api.Endpoint) *diagnostic.Diagnostic {
+	current := n
+
+	for _, segment := range strings.Split(strings.Trim(endpoint.Path(), "/"), "/") {
+		if current.name != "" && current.name[0] == '*' {
+			return current.conflict(endpoint, segment)
+		}
+
+		if segment == "" || (segment[0] != ':' && segment[0] != '*') {
+			if segment != "" && current.wildcard != nil && current.wildcard.name[0] == '*' {
+				return current.wildcard.conflict(endpoint, segment)
+			}
+
+			next, found := current.static[segment]
+
+			if !found {
+				next = newRouteNode("", endpoint)
+				current.static[segment] = next
+			}
+
+			current = next
+			continue
+		}
+
+		if current.wildcard == nil {
+			if segment[0] == '*' && len(current.static) > 0 {
+				return current.firstStatic().conflict(endpoint, segment)
+			}
+
+			current.wildcard = newRouteNode(segment, endpoint)
+		} else if current.wildcard.name != segment {
+			return current.wildcard.conflict(endpoint, segment)
+		}
+
+		current = current.wildcard
+	}
+
+	return nil
+}
+
+// Returns the static child with the lowest segment so that reported conflicts are stable.
+func (n *routeNode) firstStatic() *routeNode {
+	keys := make([]string, 0, len(n.static))
+
+	for key := range n.static {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return n.static[keys[0]]
+}
+
+func (n *routeNode) conflict(endpoint *api.Endpoint, segment string) *diagnostic.Diagnostic {
+	return diagnostic.Errorf(endpoint.Handler().Position(), codeRouteConflict,
+		"%s: '%s' in %s conflicts with %s", endpoint.Handler().Name(), segment, endpoint, n.owner).
+		WithRelated(n.owner.Handler().Position(), "conflicting route declared here")
+}
@@ -0,0 +1,69 @@
+package gin_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/YuukanOO/ease/pkg/generator"
+	"github.com/YuukanOO/ease/pkg/generator/gin"
+	"github.com/YuukanOO/ease/pkg/parser"
+	"github.com/YuukanOO/ease/pkg/parser/api"
+)
+
+func TestRouteConflicts(t *testing.T) {
+	extension := api.New()
+	result, err := parser.New(extension).Parse("github.com/YuukanOO/ease/pkg/generator/gin/testdata/conflicts")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dir := t.TempDir()
+
+	if err = generator.New(dir, gin.New(extension.Schema())).Generate(result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "server.go")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be emitted when routes conflict, got %v", err)
+	}
+
+	// Diagnostics by handler line in conflicts.go
+	reported := make(map[int]string)
+
+	for _, d := range result.Diagnostics().Items() {
+		if filepath.Base(d.Position.Filename) != "conflicts.go" || len(d.Related) != 1 {
+			t.Errorf("expected diagnostics to be located in conflicts.go with the conflicting route, got %v", d)
+			continue
+		}
+
+		reported[d.Position.Line] = fmt.Sprintf("%s %d", d.Code, d.Related[0].Position.Line)
+	}
+
+	for _, test := range []struct {
+		name     string
+		line     int
+		expected string // Code and line of the conflicting route, empty if nothing is reported
+	}{
+		{"catch-all", 4, ""},
+		{"static segment after a catch-all", 7, "route-conflict 4"},
+		{"same path with another method", 10, ""},
+		{"wildcard", 13, ""},
+		{"wildcard with another name", 16, "route-conflict 13"},
+		{"wildcard with the same name", 19, ""},
+		{"static segment", 22, ""},
+		{"catch-all after a static segment", 25, "route-conflict 22"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := reported[test.line]; got != test.expected {
+				t.Errorf("expected %q at conflicts.go:%d, got %q", test.expected, test.line, got)
+			}
+		})
+	}
+
+	if diagnostics := result.Diagnostics().Items(); len(diagnostics) != 3 {
+		t.Errorf("expected 3 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+}
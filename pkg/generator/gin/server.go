@@ -14,54 +14,123 @@ import (
 var (
 	//go:embed server.go.tmpl
 	serverTemplateContent string
-	serverTemplate        = template.Must(template.New("").Parse(serverTemplateContent))
+	serverTemplate        = template.Must(template.New(serverFilename).Funcs(generator.Funcs(nil)).Parse(serverTemplateContent))
 )
 
-type ginGenerator struct {
-	schema *api.API
+const (
+	defaultPackageName = "main"
+	serverFilename     = "server.go"
+)
+
+type (
+	ginGenerator struct {
+		schema      *api.API
+		packageName string
+		template    *template.Template
+	}
+
+	Option func(*ginGenerator)
+)
+
+func New(schema *api.API, opts ...Option) generator.Extension {
+	g := &ginGenerator{
+		schema:      schema,
+		packageName: defaultPackageName,
+		template:    serverTemplate,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
 }
 
-func New(schema *api.API) generator.Extension {
-	return &ginGenerator{
-		schema: schema,
+// WithPackageName sets the name of the generated package. The main function serving
+// the API is only generated for the main package.
+func WithPackageName(name string) Option {
+	return func(g *ginGenerator) {
+		g.packageName = name
 	}
 }
 
-type data struct {
-	generator.Context
+// WithTemplates renders files with the given templates instead of the built-in ones,
+// usually returned by ParseTemplates.
+func WithTemplates(tmpl *template.Template) Option {
+	return func(g *ginGenerator) {
+		g.template = tmpl
+	}
+}
 
-	Schema       *api.API
-	Imports      *collection.Set[*parser.Package]
-	Dependencies []*parser.Func
+// ParseTemplates returns the built-in templates overridden by the *.tmpl files of the
+// given directory, see generator.OverrideTemplates. The server.go template may be
+// overridden whole or through the following blocks:
+//
+//   - handler: the method serving an endpoint, . is an *EndpointData
+//   - response: writes the result of a successful endpoint, . is an *EndpointData
+//   - error-handler: the HttpError interface and the HandleError func
+//   - bind: the Bind func decoding the body and query of requests
+//   - main: the main func, only rendered for the main package
+//
+// Blocks must reference every package through import, gin and net/http included, as in
+// {{ $gin := import "github.com/gin-gonic/gin" }}{{ $gin }}.Context.
+func ParseTemplates(dir string) (*template.Template, error) {
+	return generator.OverrideTemplates(serverTemplate, dir)
 }
 
+func (g *ginGenerator) Name() string { return "gin" }
+
+type (
+	data struct {
+		generator.Context
+
+		Schema       *api.API
+		PackageName  string
+		Dependencies []*parser.Dependency
+		Middlewares  []*api.Middleware // Middlewares used by at least one endpoint
+		Verifiers    []*api.Verifier   // Verifiers used by at least one endpoint
+	}
+
+	// Data given to blocks rendering a single endpoint, everything given to the server.go
+	// template remains available.
+	EndpointData struct {
+		*data
+
+		Endpoint *api.Endpoint
+		Result   string // Name of the variable holding the first value returned by the handler
+	}
+)
+
 func (g *ginGenerator) Generate(ctx generator.Context) error {
-	fields := collection.NewSet[*parser.Type]()
+	// Do not emit anything if routes could not be registered by gin
+	if diagnostics := checkRoutes(g.schema.Endpoints()); len(diagnostics) > 0 {
+		ctx.Diagnostics().Add(diagnostics...)
+		return nil
+	}
+
+	fields := collection.NewSet[*parser.TypeExpr]()
+	middlewares := collection.NewSet[*api.Middleware]()
+	verifiers := collection.NewSet[*api.Verifier]()
 	templateData := &data{
-		Context: ctx,
-		Schema:  g.schema,
-		Imports: collection.NewSet[*parser.Package](),
+		Context:     ctx,
+		Schema:      g.schema,
+		PackageName: g.packageName,
+	}
+
+	for _, group := range g.schema.Groups() {
+		for _, middleware := range group.Middlewares() {
+			middlewares.Set(middleware.Name(), middleware)
+		}
 	}
 
 	// To build the Server struct, we need to find every handler which as a receiver
 	for _, endpoint := range g.schema.Endpoints() {
-		// If its a raw endpoint, we already import the net/http package so don't need to loop
-		// through the params
-		if !endpoint.IsRaw() {
-			pkg := endpoint.Handler().Package()
-
-			if pkg != nil {
-				templateData.Imports.Set(pkg.Path(), pkg)
-			}
-
-			// Register each package used by params
-			for _, param := range endpoint.Handler().Params() {
-				pkg = param.Type().Package()
-
-				if pkg != nil {
-					templateData.Imports.Set(pkg.Path(), pkg)
-				}
-			}
+		for _, middleware := range endpoint.Middlewares() {
+			middlewares.Set(middleware.Name(), middleware)
+		}
+
+		if security := endpoint.Security(); security != nil {
+			verifiers.Set(security.Verifier().Scheme(), security.Verifier())
 		}
 
 		recv := endpoint.Handler().Recv()
@@ -70,9 +139,29 @@ func (g *ginGenerator) Generate(ctx generator.Context) error {
 			continue
 		}
 
-		recvTyp := recv.Type()
+		fields.Set(recv.TypeExpr().Instance(), recv.TypeExpr())
+	}
+
+	templateData.Middlewares = middlewares.Items()
+
+	// Middlewares may need a receiver and dependencies too
+	for _, middleware := range templateData.Middlewares {
+		if recv := middleware.Handler().Recv(); recv != nil {
+			fields.Set(recv.TypeExpr().Instance(), recv.TypeExpr())
+		}
+
+		for _, dep := range middleware.Dependencies() {
+			fields.Set(dep.TypeExpr().Instance(), dep.TypeExpr())
+		}
+	}
 
-		fields.Set(recvTyp.String(), recvTyp)
+	templateData.Verifiers = verifiers.Items()
+
+	// Verifiers may need a receiver too
+	for _, verifier := range templateData.Verifiers {
+		if recv := verifier.Handler().Recv(); recv != nil {
+			fields.Set(recv.TypeExpr().Instance(), recv.TypeExpr())
+		}
 	}
 
 	resolved, err := ctx.Funcs().Resolve(fields.Items()...)
@@ -81,18 +170,15 @@ func (g *ginGenerator) Generate(ctx generator.Context) error {
 		return err
 	}
 
-	templateData.Dependencies = resolved.Funcs()
+	templateData.Dependencies = resolved.Dependencies()
 
-	// Add packages needed by dependencies
-	for _, fn := range templateData.Dependencies {
-		pkg := fn.Package()
-
-		if pkg == nil {
-			continue
-		}
+	return ctx.EmitTemplate(serverFilename, g.template, templateData)
+}
 
-		templateData.Imports.Set(pkg.Path(), pkg)
+func (d *data) WithEndpoint(endpoint *api.Endpoint) *EndpointData {
+	return &EndpointData{
+		data:     d,
+		Endpoint: endpoint,
+		Result:   d.Identifier("result", "easeGinHandlerResult"),
 	}
-
-	return ctx.EmitTemplate("server.go", serverTemplate, templateData)
 }
@@ -0,0 +1,25 @@
+package conflicts
+
+// ease:api path=/files/*path
+func Files(path string) {}
+
+// ease:api path=/files/readme
+func Readme() {}
+
+// ease:api method=POST path=/files/readme
+func CreateReadme() {}
+
+// ease:api path=/users/:id
+func User(id string) {}
+
+// ease:api path=/users/:name/posts
+func UserPosts(name string) {}
+
+// ease:api path=/users/:id/comments
+func UserComments(id string) {}
+
+// ease:api path=/static/index
+func Index() {}
+
+// ease:api path=/static/*file
+func Static(file string) {}
@@ -0,0 +1,150 @@
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/YuukanOO/ease/pkg/crypto"
+	"github.com/YuukanOO/ease/pkg/naming"
+)
+
+// Length of a sha256 hash in hexadecimal, the maximum length of the hash in identifiers.
+const hashLength = 64
+
+// Identifiers allocated while generating, they are unique across every emitted file and
+// only depend on the order in which they are requested so the output is stable.
+type identifiers struct {
+	mu     sync.Mutex
+	byKey  map[string]string // Identifier allocated for each prefix and key
+	owners map[string]string // Prefix and key which own each allocated identifier
+}
+
+func newIdentifiers() *identifiers {
+	return &identifiers{
+		byKey:  make(map[string]string),
+		owners: make(map[string]string),
+	}
+}
+
+// Returns the identifier of the given prefix and key, allocating it if needed. Readable
+// identifiers are meant for fields and methods, see readable, every other one is the
+// prefix followed by a hash of the key, extended until it is not used by another key.
+func (ids *identifiers) get(prefix, key string, readable bool) string {
+	ids.mu.Lock()
+	defer ids.mu.Unlock()
+
+	owner := prefix + "\x00" + key
+
+	if id, found := ids.byKey[owner]; found {
+		return id
+	}
+
+	id := ""
+
+	if readable {
+		id = ids.readable(prefix, key)
+	}
+
+	if id == "" {
+		id = ids.hashed(sanitize(prefix), key)
+	}
+
+	ids.byKey[owner] = id
+	ids.owners[id] = owner
+
+	return id
+}
+
+// Returns the first available of the prefix in camelCase, followed by the package of the
+// key, then by a counter: update, updateTodo, update2. Keys are expected to be qualified
+// names such as example.com/todo.(*Service).Update. Returns an empty string if the prefix
+// can not start an identifier.
+func (ids *identifiers) readable(prefix, key string) string {
+	base := naming.Camel(prefix)
+
+	// Keywords can not be used as is but can still be suffixed
+	if base == "" || (!token.IsIdentifier(base) && !token.IsKeyword(base)) {
+		return ""
+	}
+
+	if ids.available(base, false) {
+		return base
+	}
+
+	if pkg := keyPackage(key); pkg != "" {
+		if id := base + naming.Pascal(pkg); ids.available(id, false) {
+			return id
+		}
+	}
+
+	for i := 2; ; i++ {
+		if id := base + strconv.Itoa(i); ids.available(id, false) {
+			return id
+		}
+	}
+}
+
+func (ids *identifiers) hashed(prefix, key string) string {
+	for length := identifierPrefixLength; length <= hashLength; length += 2 {
+		if id := prefix + "_" + crypto.Prefix(key, length); ids.available(id, true) {
+			return id
+		}
+	}
+
+	// Only reached for prefixes which are the same once sanitized, such as a-b and a_b
+	for i := 2; ; i++ {
+		if id := fmt.Sprintf("%s_%s_%d", prefix, crypto.Prefix(key, hashLength), i); ids.available(id, true) {
+			return id
+		}
+	}
+}
+
+// Checks if the identifier is valid and not already allocated. Local identifiers must not
+// shadow a predeclared identifier such as string or len either, fields and methods can
+// not shadow anything.
+func (ids *identifiers) available(id string, local bool) bool {
+	_, allocated := ids.owners[id]
+
+	return !allocated && token.IsIdentifier(id) && (!local || types.Universe.Lookup(id) == nil)
+}
+
+// Returns the last element of the package path a qualified name starts with, such as
+// todo for example.com/todo.(*Service).Update GET /todos, or an empty string if the name
+// is not qualified.
+func keyPackage(key string) string {
+	if i := strings.IndexAny(key, " ["); i >= 0 {
+		key = key[:i]
+	}
+
+	key = strings.TrimLeft(key, "*")
+	key = key[strings.LastIndex(key, "/")+1:]
+	pkg, _, qualified := strings.Cut(key, ".")
+
+	if !qualified {
+		return ""
+	}
+
+	return pkg
+}
+
+// Replaces characters which can not appear in an identifier by an underscore.
+func sanitize(prefix string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+
+		return '_'
+	}, prefix)
+
+	if sanitized == "" || unicode.IsDigit([]rune(sanitized)[0]) {
+		return "_" + sanitized
+	}
+
+	return sanitized
+}
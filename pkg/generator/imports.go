@@ -0,0 +1,282 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Placeholders returned by Import are replaced once the whole file is known since the name
+// of a package depends on every other identifier of the file.
+const importPlaceholderPrefix = "__ease_import_"
+
+type (
+	// Packages imported by the file being emitted.
+	fileImports struct {
+		packages []*importedPackage // In registration order
+		byPath   map[string]*importedPackage
+	}
+
+	importedPackage struct {
+		path        string
+		name        string // Name declared by the package itself
+		placeholder string
+	}
+)
+
+func newFileImports() *fileImports {
+	return &fileImports{byPath: make(map[string]*importedPackage)}
+}
+
+// Registers the package at the given path and returns the placeholder to use in place of
+// its name. Without a declared name, the package is assumed to be named after its path.
+func (f *fileImports) add(importPath, name string) string {
+	if pkg, found := f.byPath[importPath]; found {
+		return pkg.placeholder
+	}
+
+	if name == "" {
+		name = assumedName(importPath)
+	}
+
+	// Paths such as example.com/1password do not give a valid name, a sanitized one always
+	// differs from the assumed one so an explicit alias is written
+	if !token.IsIdentifier(name) {
+		name = sanitize(name)
+	}
+
+	pkg := &importedPackage{
+		path:        importPath,
+		name:        name,
+		placeholder: fmt.Sprintf("%s%d__", importPlaceholderPrefix, len(f.packages)),
+	}
+
+	f.packages = append(f.packages, pkg)
+	f.byPath[importPath] = pkg
+
+	return pkg.placeholder
+}
+
+// Replaces placeholders of registered packages in the given Go source and rewrites its
+// imports as goimports would: unused ones are removed, packages are referenced by their
+// own name unless it conflicts with another identifier of the file, in which case alias
+// is used to name them, and standard packages are grouped before the other ones.
+//
+// Sources which can not be parsed only get their placeholders replaced so the syntax
+// error is reported when formatting them.
+func (f *fileImports) resolve(src []byte, alias func(name, path string) string) []byte {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "", src, goparser.SkipObjectResolution)
+
+	if err != nil {
+		return f.replace(src, func(pkg *importedPackage) string { return pkg.name })
+	}
+
+	var (
+		names      = make(map[string]bool) // Every identifier of the file but placeholders
+		qualifiers = make(map[string]bool) // Identifiers used to select something, such as http in http.StatusOK
+		declared   []*importedPackage      // Imports written in the source
+		cuts       [][2]int                // Offsets of import declarations
+	)
+
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			cuts = append(cuts, [2]int{fset.Position(gen.Pos()).Offset, fset.Position(gen.End()).Offset})
+
+			for _, spec := range gen.Specs {
+				declared = append(declared, declaredImport(spec.(*ast.ImportSpec)))
+			}
+
+			continue
+		}
+
+		ast.Inspect(decl, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.SelectorExpr:
+				if ident, ok := n.X.(*ast.Ident); ok {
+					qualifiers[ident.Name] = true
+				}
+
+				// Selected names can not conflict with package names
+				ast.Inspect(n.X, func(node ast.Node) bool {
+					if ident, ok := node.(*ast.Ident); ok && !f.isPlaceholder(ident.Name) {
+						names[ident.Name] = true
+					}
+
+					return true
+				})
+
+				return false
+			case *ast.Ident:
+				if !f.isPlaceholder(n.Name) {
+					names[n.Name] = true
+				}
+			}
+
+			return true
+		})
+	}
+
+	var (
+		imports []*importedPackage
+		count   = make(map[string]int)
+	)
+
+	// Imports written in the source are kept if they are used, by their name or by placeholders
+	for _, pkg := range declared {
+		if _, registered := f.byPath[pkg.path]; registered || qualifiers[pkg.name] || pkg.name == "_" || pkg.name == "." {
+			imports = append(imports, pkg)
+		}
+	}
+
+	for _, pkg := range f.packages {
+		if !isDeclared(declared, pkg.path) {
+			count[pkg.name]++
+		}
+	}
+
+	// Other packages are named after themselves unless another package or identifier uses it
+	for _, pkg := range f.packages {
+		if isDeclared(declared, pkg.path) {
+			continue
+		}
+
+		name := pkg.name
+
+		if count[name] > 1 || names[name] {
+			name = alias(name, pkg.path)
+		}
+
+		names[name] = true
+		imports = append(imports, &importedPackage{path: pkg.path, name: name})
+	}
+
+	var out bytes.Buffer
+
+	nameEnd := fset.Position(file.Name.End()).Offset
+	out.Write(src[:nameEnd])
+	out.WriteString("\n\n")
+	writeImports(&out, imports)
+
+	last := nameEnd
+
+	for _, cut := range cuts {
+		out.Write(src[last:cut[0]])
+		last = cut[1]
+	}
+
+	out.Write(src[last:])
+
+	return f.replace(out.Bytes(), func(pkg *importedPackage) string {
+		for _, imported := range imports {
+			if imported.path == pkg.path {
+				return imported.name
+			}
+		}
+
+		return pkg.name
+	})
+}
+
+func isDeclared(declared []*importedPackage, importPath string) bool {
+	for _, pkg := range declared {
+		if pkg.path == importPath {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Replaces placeholders by the name returned by the given function.
+func (f *fileImports) replace(src []byte, name func(*importedPackage) string) []byte {
+	for _, pkg := range f.packages {
+		src = bytes.ReplaceAll(src, []byte(pkg.placeholder), []byte(name(pkg)))
+	}
+
+	return src
+}
+
+func (f *fileImports) isPlaceholder(name string) bool {
+	return strings.HasPrefix(name, importPlaceholderPrefix)
+}
+
+// Writes an import declaration with standard packages first, each group being sorted by
+// path. Names are only written when they differ from the one assumed from the path.
+func writeImports(out *bytes.Buffer, imports []*importedPackage) {
+	if len(imports) == 0 {
+		return
+	}
+
+	sort.SliceStable(imports, func(i, j int) bool {
+		if std := isStandard(imports[i].path); std != isStandard(imports[j].path) {
+			return std
+		}
+
+		return imports[i].path < imports[j].path
+	})
+
+	out.WriteString("import (\n")
+
+	for i, pkg := range imports {
+		if i > 0 && isStandard(imports[i-1].path) != isStandard(pkg.path) {
+			out.WriteString("\n")
+		}
+
+		out.WriteString("\t")
+
+		if pkg.name != assumedName(pkg.path) {
+			out.WriteString(pkg.name + " ")
+		}
+
+		out.WriteString(strconv.Quote(pkg.path) + "\n")
+	}
+
+	out.WriteString(")\n")
+}
+
+func declaredImport(spec *ast.ImportSpec) *importedPackage {
+	importPath, _ := strconv.Unquote(spec.Path.Value)
+	pkg := &importedPackage{path: importPath, name: assumedName(importPath)}
+
+	if spec.Name != nil {
+		pkg.name = spec.Name.Name
+	}
+
+	return pkg
+}
+
+// Standard packages have no dot in their first path element, as goimports assumes.
+func isStandard(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// Returns the name a package is expected to declare from its path, which skips major
+// versions and go- prefixes: gopkg.in/yaml.v3 gives yaml and example.com/go-mod/v2 gives mod.
+func assumedName(importPath string) string {
+	base := path.Base(importPath)
+
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil && path.Dir(importPath) != "." {
+			base = path.Base(path.Dir(importPath))
+		}
+	}
+
+	base = strings.TrimPrefix(base, "go-")
+
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}); i >= 0 {
+		base = base[:i]
+	}
+
+	return base
+}
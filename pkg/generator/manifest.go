@@ -0,0 +1,146 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	ManifestFilename      = ".ease-manifest.json"
+	manifestFormatVersion = 3
+	legacyGenerator       = "" // Generator of files recorded by the first manifest format
+)
+
+type (
+	// Manifest records the output directory of each generator and the files it emitted
+	// there, so they can be removed once they are not emitted anymore, even if the
+	// generator now writes somewhere else.
+	Manifest struct {
+		Version    int                `json:"version"`
+		Outputs    map[string]*Output `json:"outputs"`              // Outputs by generator
+		Generators map[string]Files   `json:"generators,omitempty"` // Deprecated: second format, migrated on read
+		Files      map[string]string  `json:"files,omitempty"`      // Deprecated: first format, migrated on read
+	}
+
+	// Files emitted by a generator in its output directory.
+	Output struct {
+		Dir   string `json:"dir"` // Output directory, relative to the manifest one
+		Files Files  `json:"files"`
+	}
+
+	Files map[string]string // Path relative to the output directory to the sha256 of its content
+)
+
+func newManifest() *Manifest {
+	return &Manifest{
+		Version: manifestFormatVersion,
+		Outputs: make(map[string]*Output),
+	}
+}
+
+// Reads the manifest stored in the given directory. A missing manifest results in an
+// empty one.
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFilename))
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return newManifest(), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := newManifest()
+
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+
+	// Previous formats were stored in the output directory itself
+	if len(manifest.Files) > 0 {
+		manifest.Outputs[legacyGenerator] = &Output{Dir: ".", Files: manifest.Files}
+	}
+
+	for generator, files := range manifest.Generators {
+		manifest.Outputs[generator] = &Output{Dir: ".", Files: files}
+	}
+
+	manifest.Files = nil
+	manifest.Generators = nil
+	manifest.Version = manifestFormatVersion
+
+	return manifest, nil
+}
+
+func (m *Manifest) add(generator, dir, path string, data []byte) {
+	output, found := m.Outputs[generator]
+
+	if !found {
+		output = &Output{Dir: filepath.ToSlash(dir), Files: make(Files)}
+		m.Outputs[generator] = output
+	}
+
+	output.Files[filepath.ToSlash(path)] = hash(data)
+}
+
+// Checks if the given path, relative to the manifest directory, has been emitted by any
+// generator.
+func (m *Manifest) Has(path string) bool {
+	for _, output := range m.Outputs {
+		for file := range output.Files {
+			if output.path(file) == path {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Checks if no file is recorded at all.
+func (m *Manifest) IsEmpty() bool {
+	for _, output := range m.Outputs {
+		if len(output.Files) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m *Manifest) encode() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+// Returns the path of the given file relative to the manifest directory.
+func (o *Output) path(file string) string {
+	return path.Join(o.Dir, file)
+}
+
+// Writes the given file unless it already exists with the same content so unchanged
+// outputs keep their modification time.
+func writeFile(path string, data []byte) error {
+	if existing, err := os.ReadFile(path); err == nil && hash(existing) == hash(data) {
+		return nil
+	}
+
+	return os.WriteFile(path, data, defaultPermissions)
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,206 @@
+// Package plugin runs generators living out of process, much like protoc plugins. A plugin
+// is an executable named ease-gen-<name> which receives a JSON Request on its standard
+// input and writes a JSON Response on its standard output.
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/YuukanOO/ease/pkg/diagnostic"
+	"github.com/YuukanOO/ease/pkg/generator"
+	"github.com/YuukanOO/ease/pkg/parser"
+)
+
+// Prefix of plugins executables, looked up in the PATH.
+const ExecutablePrefix = "ease-gen-"
+
+var (
+	ErrNotFound           = errors.New("generator plugin not found")
+	ErrFailed             = errors.New("generator plugin failed")
+	ErrInvalidResponse    = errors.New("invalid generator plugin response")
+	ErrUnsupportedVersion = errors.New("unsupported plugin protocol version")
+)
+
+type (
+	pluginGenerator struct {
+		name        string
+		command     []string
+		extensions  []parser.Extension
+		packageName string
+		options     map[string]string
+	}
+
+	Option func(*pluginGenerator)
+)
+
+// New builds a generator running the ease-gen-<name> plugin.
+func New(name string, opts ...Option) generator.Extension {
+	g := &pluginGenerator{
+		name:    name,
+		command: []string{ExecutablePrefix + name},
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// Lookup returns the path of the ease-gen-<name> executable or ErrNotFound.
+func Lookup(name string) (string, error) {
+	path, err := exec.LookPath(ExecutablePrefix + name)
+
+	if err != nil {
+		return "", fmt.Errorf("%w: %s%s is not in the PATH", ErrNotFound, ExecutablePrefix, name)
+	}
+
+	return path, nil
+}
+
+// WithCommand runs the given command instead of looking for ease-gen-<name> in the PATH.
+func WithCommand(name string, args ...string) Option {
+	return func(g *pluginGenerator) {
+		g.command = append([]string{name}, args...)
+	}
+}
+
+// WithExtensions adds what the given parser extensions extracted to the request document.
+func WithExtensions(extensions ...parser.Extension) Option {
+	return func(g *pluginGenerator) {
+		g.extensions = extensions
+	}
+}
+
+// WithPackageName sets the name of the generated package sent to the plugin.
+func WithPackageName(name string) Option {
+	return func(g *pluginGenerator) {
+		g.packageName = name
+	}
+}
+
+// WithOptions sets generator specific options sent to the plugin as is.
+func WithOptions(options map[string]string) Option {
+	return func(g *pluginGenerator) {
+		g.options = options
+	}
+}
+
+func (g *pluginGenerator) Name() string { return g.name }
+
+func (g *pluginGenerator) Generate(ctx generator.Context) error {
+	response, err := g.run(&Request{
+		Version:     ProtocolVersion,
+		Generator:   g.name,
+		PackageName: g.packageName,
+		Options:     g.options,
+		Document:    parser.Inspect(ctx, g.extensions...),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if response.Error != "" {
+		return fmt.Errorf("%w: %s: %s", ErrFailed, g.name, response.Error)
+	}
+
+	for _, d := range response.Diagnostics {
+		ctx.Diagnostics().Add(restoreDiagnostic(g.name, d))
+	}
+
+	// Do not emit an incomplete output
+	if ctx.Diagnostics().HasErrors() {
+		return nil
+	}
+
+	for _, file := range response.Files {
+		if err := ctx.EmitFile(file.Path, []byte(file.Content)); err != nil {
+			return fmt.Errorf("%s: %s: %w", g.name, file.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// Runs the plugin with the given request and decodes its response. The error output of
+// the plugin is included in the returned error if it fails.
+func (g *pluginGenerator) run(request *Request) (*Response, error) {
+	input, err := json.Marshal(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(g.command[0], g.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err = cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %s is not in the PATH", ErrNotFound, g.command[0])
+		}
+
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			err = fmt.Errorf("%v\n%s", err, output)
+		}
+
+		return nil, fmt.Errorf("%w: %s: %v", ErrFailed, g.name, err)
+	}
+
+	var response Response
+
+	if err = json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("%w from %s: %v", ErrInvalidResponse, g.name, err)
+	}
+
+	for _, file := range response.Files {
+		// Plugins must not write anything outside of the output directory
+		if !filepath.IsLocal(filepath.FromSlash(file.Path)) {
+			return nil, fmt.Errorf("%w from %s: %q is not a relative path inside the output directory", ErrInvalidResponse, g.name, file.Path)
+		}
+	}
+
+	return &response, nil
+}
+
+// Builds a diagnostic reported by a plugin, the generator name is used as its code if
+// the plugin did not give one.
+func restoreDiagnostic(name string, d *parser.DocDiagnostic) *diagnostic.Diagnostic {
+	restored := &diagnostic.Diagnostic{
+		Severity: diagnostic.SeverityInfo,
+		Code:     d.Code,
+		Message:  d.Message,
+	}
+
+	switch d.Severity {
+	case diagnostic.SeverityError.String():
+		restored.Severity = diagnostic.SeverityError
+	case diagnostic.SeverityWarning.String():
+		restored.Severity = diagnostic.SeverityWarning
+	}
+
+	if restored.Code == "" {
+		restored.Code = name
+	}
+
+	if d.Position != nil {
+		restored.Position = token.Position{
+			Filename: d.Position.File,
+			Line:     d.Position.Line,
+			Column:   d.Position.Column,
+		}
+	}
+
+	return restored
+}
@@ -0,0 +1,128 @@
+package plugin_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/YuukanOO/ease/pkg/generator"
+	"github.com/YuukanOO/ease/pkg/generator/plugin"
+	"github.com/YuukanOO/ease/pkg/parser"
+)
+
+// When set, the test binary acts as a plugin with the given behavior.
+const behaviorEnv = "EASE_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if behavior := os.Getenv(behaviorEnv); behavior != "" {
+		plugin.Serve(func(r *plugin.Request) (*plugin.Response, error) { return serve(behavior, r) })
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+func serve(behavior string, r *plugin.Request) (*plugin.Response, error) {
+	switch behavior {
+	case "funcs":
+		var names []string
+
+		for _, fn := range r.Document.Funcs {
+			names = append(names, fn.ID)
+		}
+
+		return &plugin.Response{Files: []*plugin.File{
+			{Path: "funcs.txt", Content: strings.Join(names, "\n")},
+			{Path: "sub/gen.go", Content: fmt.Sprintf("%s; DO NOT EDIT\npackage   %s", generator.GeneratedHeader, r.PackageName)},
+		}}, nil
+	case "diagnostics":
+		return &plugin.Response{
+			Files:       []*plugin.File{{Path: "never.txt"}},
+			Diagnostics: []*parser.DocDiagnostic{{Severity: "error", Message: "unsupported " + r.Options["mode"]}},
+		}, nil
+	case "escape":
+		return &plugin.Response{Files: []*plugin.File{{Path: "../escaped.txt"}}}, nil
+	default:
+		return nil, errors.New("unknown behavior")
+	}
+}
+
+func generate(t *testing.T, dir string, behavior string, opts ...plugin.Option) (parser.Result, error) {
+	t.Setenv(behaviorEnv, behavior)
+
+	result, err := parser.New().Parse("github.com/YuukanOO/ease/pkg/parser/testdata")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	opts = append(opts, plugin.WithCommand(os.Args[0]))
+
+	return result, generator.New(dir, plugin.New("test", opts...)).Generate(result)
+}
+
+func TestPlugin(t *testing.T) {
+	t.Run("should emit files returned by the plugin", func(t *testing.T) {
+		dir := t.TempDir()
+
+		if _, err := generate(t, dir, "funcs", plugin.WithPackageName("api")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		funcs, err := os.ReadFile(filepath.Join(dir, "funcs.txt"))
+
+		if err != nil || !strings.Contains(string(funcs), "github.com/YuukanOO/ease/pkg/parser/testdata.(*TestService).GetByID") {
+			t.Errorf("expected funcs to be written as is, got %q, %v", funcs, err)
+		}
+
+		source, err := os.ReadFile(filepath.Join(dir, "sub", "gen.go"))
+
+		if err != nil || !strings.HasSuffix(string(source), "\npackage api\n") {
+			t.Errorf("expected go files to be formatted, got %q, %v", source, err)
+		}
+
+		manifest, err := generator.ReadManifest(dir)
+
+		if err != nil || !manifest.Has("sub/gen.go") {
+			t.Errorf("expected emitted files to be in the manifest, got %v, %v", manifest, err)
+		}
+	})
+
+	t.Run("should report diagnostics and emit nothing on errors", func(t *testing.T) {
+		dir := t.TempDir()
+		result, err := generate(t, dir, "diagnostics", plugin.WithOptions(map[string]string{"mode": "strict"}))
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var found bool
+
+		for _, d := range result.Diagnostics().Items() {
+			found = found || (d.Code == "test" && d.Message == "unsupported strict")
+		}
+
+		if !found {
+			t.Errorf("expected the plugin diagnostic to be reported, got %v", result.Diagnostics().Items())
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "never.txt")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected nothing to be emitted, got %v", err)
+		}
+	})
+
+	t.Run("should reject files outside of the output directory", func(t *testing.T) {
+		if _, err := generate(t, t.TempDir(), "escape"); !errors.Is(err, plugin.ErrInvalidResponse) {
+			t.Errorf("expected ErrInvalidResponse, got %v", err)
+		}
+	})
+
+	t.Run("should return the plugin error", func(t *testing.T) {
+		if _, err := generate(t, t.TempDir(), "unknown"); !errors.Is(err, plugin.ErrFailed) || !strings.Contains(err.Error(), "unknown behavior") {
+			t.Errorf("expected ErrFailed with the plugin error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/YuukanOO/ease/pkg/parser"
+)
+
+// Version of the protocol, bumped whenever a field of the request or the response is
+// removed or its meaning changes.
+const ProtocolVersion = 1
+
+type (
+	// Written as JSON on the standard input of the plugin.
+	Request struct {
+		Version     int               `json:"version"`
+		Generator   string            `json:"generator"`             // Name of the generator, the plugin binary being ease-gen-<name>
+		PackageName string            `json:"packageName,omitempty"` // Name of the generated package if configured
+		Options     map[string]string `json:"options,omitempty"`     // Generator specific options from the configuration
+		Document    *parser.Document  `json:"document"`              // What ease understood, extensions results included
+	}
+
+	// Expected as JSON on the standard output of the plugin. Diagnostics are reported
+	// along with the ones of the parser, files are only written if none of them is an error.
+	Response struct {
+		Files       []*File                 `json:"files"`
+		Diagnostics []*parser.DocDiagnostic `json:"diagnostics,omitempty"`
+		Error       string                  `json:"error,omitempty"` // Fails the generation without any position
+	}
+
+	// File to emit, relative to the output directory.
+	File struct {
+		Path    string `json:"path"` // Slash separated path
+		Content string `json:"content"`
+	}
+)
+
+// Serve implements the plugin side of the protocol: it reads the request from the standard
+// input, calls fn and writes its response on the standard output. A returned error is
+// sent as the response error. It is meant to be called from the main of a plugin.
+func Serve(fn func(*Request) (*Response, error)) {
+	if err := serve(os.Stdin, os.Stdout, fn); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func serve(r io.Reader, w io.Writer, fn func(*Request) (*Response, error)) error {
+	var request Request
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("could not decode the request: %w", err)
+	}
+
+	if request.Version != ProtocolVersion {
+		return fmt.Errorf("%w: got %d, expected %d", ErrUnsupportedVersion, request.Version, ProtocolVersion)
+	}
+
+	response, err := fn(&request)
+
+	if err != nil {
+		response = &Response{Error: err.Error()}
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Extension of template override files.
+const TemplateExtension = ".tmpl"
+
+var ErrInvalidTemplate = errors.New("invalid template override")
+
+// OverrideTemplates returns a copy of the given built-in templates where the ones defined
+// by *.tmpl files of the given directory take precedence. A file named after a built-in
+// template, such as server.go.tmpl, replaces it whole and {{define}} blocks replace the
+// built-in blocks of the same name, so functions and data given to the built-in templates
+// are available to overrides too.
+//
+// Overrides are validated right away: they must parse, only define built-in templates and
+// only reference existing ones.
+func OverrideTemplates(builtin *template.Template, dir string) (*template.Template, error) {
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	overridden, err := builtin.Clone()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != TemplateExtension {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		if err = override(overridden, builtin, path); err != nil {
+			return nil, fmt.Errorf("%s: %w: %v", path, ErrInvalidTemplate, err)
+		}
+	}
+
+	for _, tmpl := range overridden.Templates() {
+		if tmpl.Tree == nil {
+			continue
+		}
+
+		for _, name := range references(tmpl.Tree.Root, nil) {
+			if t := overridden.Lookup(name); t == nil || t.Tree == nil {
+				return nil, fmt.Errorf("%s: %w: %s references %s which does not exist", dir, ErrInvalidTemplate, tmpl.Name(), name)
+			}
+		}
+	}
+
+	// A whole override replaces the template in the set but not the clone itself
+	return overridden.Lookup(builtin.Name()), nil
+}
+
+// Parses the override file at the given path in the overridden set, the file name without
+// its extension is the name of the template it defines outside of {{define}} blocks.
+func override(overridden, builtin *template.Template, path string) error {
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), TemplateExtension)
+
+	// Functions are checked by the real parse below, this one only lists definitions
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	trees := make(map[string]*parse.Tree)
+
+	if _, err = tree.Parse(string(content), "", "", trees); err != nil {
+		return err
+	}
+
+	for defined, t := range trees {
+		// Files only made of {{define}} blocks do not define a template named after them
+		if defined == name && parse.IsEmptyTree(t.Root) {
+			continue
+		}
+
+		if builtin.Lookup(defined) == nil {
+			return fmt.Errorf("%s is not a built-in template, available ones: %s", defined, strings.Join(templateNames(builtin), ", "))
+		}
+	}
+
+	_, err = overridden.New(name).Parse(string(content))
+
+	return err
+}
+
+// Appends names of templates invoked by the given node and its children.
+func references(node parse.Node, names []string) []string {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return names
+		}
+
+		for _, child := range n.Nodes {
+			names = references(child, names)
+		}
+	case *parse.IfNode:
+		names = references(n.ElseList, references(n.List, names))
+	case *parse.RangeNode:
+		names = references(n.ElseList, references(n.List, names))
+	case *parse.WithNode:
+		names = references(n.ElseList, references(n.List, names))
+	case *parse.TemplateNode:
+		names = append(names, n.Name)
+	}
+
+	return names
+}
+
+// Retrieve sorted names of the given templates which can be overridden.
+func templateNames(tmpl *template.Template) []string {
+	var names []string
+
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			names = append(names, t.Name())
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,97 @@
+// Package naming converts identifiers between the usual casing conventions.
+package naming
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Words splits the given identifier on non alphanumeric characters and case changes,
+// keeping acronyms together: HTTPServer_url gives HTTP, Server and url.
+func Words(s string) []string {
+	var (
+		words []string
+		runes = []rune(s)
+		start = -1
+	)
+
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+
+			continue
+		}
+
+		if start >= 0 && unicode.IsUpper(r) {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			// userID or HTTPServer, a new word starts on this rune
+			if !unicode.IsUpper(previous) || nextIsLower {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+
+		if start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
+
+// Camel converts the identifier to camelCase, acronyms are kept uppercase unless they
+// start the identifier: HTTP_server_id gives httpServerId and userID stays userID.
+func Camel(s string) string {
+	words := Words(s)
+
+	if len(words) == 0 {
+		return ""
+	}
+
+	return strings.ToLower(words[0]) + pascal(words[1:])
+}
+
+// Pascal converts the identifier to PascalCase, acronyms are kept uppercase.
+func Pascal(s string) string {
+	return pascal(Words(s))
+}
+
+// Snake converts the identifier to snake_case.
+func Snake(s string) string {
+	return join(Words(s), "_")
+}
+
+// Kebab converts the identifier to kebab-case.
+func Kebab(s string) string {
+	return join(Words(s), "-")
+}
+
+func pascal(words []string) string {
+	var b strings.Builder
+
+	for _, word := range words {
+		if strings.ToUpper(word) == word {
+			b.WriteString(word)
+			continue
+		}
+
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	return b.String()
+}
+
+func join(words []string, separator string) string {
+	return strings.ToLower(strings.Join(words, separator))
+}
@@ -0,0 +1,39 @@
+package naming_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/YuukanOO/ease/pkg/naming"
+)
+
+func TestNaming(t *testing.T) {
+	t.Run("should split words on separators and case changes", func(t *testing.T) {
+		for input, expected := range map[string]string{
+			"userID":           "user ID",
+			"HTTPServer":       "HTTP Server",
+			"get-todo_by.id":   "get todo by id",
+			"Page2Items":       "Page2 Items",
+			"  spaces  around": "spaces around",
+			"":                 "",
+		} {
+			if words := strings.Join(naming.Words(input), " "); words != expected {
+				t.Errorf("expected %q to be split as %q, got %q", input, expected, words)
+			}
+		}
+	})
+
+	t.Run("should convert between conventions", func(t *testing.T) {
+		for input, expected := range map[string][4]string{
+			"userID":        {"userID", "UserID", "user_id", "user-id"},
+			"HTTP_server":   {"httpServer", "HTTPServer", "http_server", "http-server"},
+			"get todo list": {"getTodoList", "GetTodoList", "get_todo_list", "get-todo-list"},
+		} {
+			got := [4]string{naming.Camel(input), naming.Pascal(input), naming.Snake(input), naming.Kebab(input)}
+
+			if got != expected {
+				t.Errorf("expected %q to be converted to %v, got %v", input, expected, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/YuukanOO/ease/pkg/parser"
+)
+
+const (
+	SchemeTypeBearer SchemeType = "bearer" // Credentials are extracted from the Authorization: Bearer header
+	SchemeTypeApiKey SchemeType = "apikey" // Credentials are extracted from a custom header
+
+	schemeDirectiveParam = "scheme"
+	typeDirectiveParam   = "type"
+	headerDirectiveParam = "header"
+	scopesDirectiveParam = "scopes"
+	defaultApiKeyHeader  = "X-API-Key"
+)
+
+type (
+	SchemeType string // Type of a security scheme
+
+	// Represents a function used to verify credentials of a security scheme and returns the
+	// authenticated principal. It is declared with the verifier directive and must have one of
+	// the following signatures:
+	//
+	//	func(context.Context, string) (P, error)
+	//	func(context.Context, string, []string) (P, error)
+	//
+	// The optional last param receives the scopes required by the endpoint, endpoints can not
+	// require scopes from a verifier without it. If the returned error implements the
+	// generated HttpError interface, its status will be used, else a 401 is returned.
+	Verifier struct {
+		scheme    string
+		typ       SchemeType
+		header    string
+		handler   *parser.Func
+		principal *parser.Var
+	}
+
+	// Security requirements of a single endpoint.
+	Security struct {
+		verifier *Verifier
+		scopes   []string
+	}
+)
+
+func (v *Verifier) Scheme() string         { return v.scheme }
+func (v *Verifier) Type() SchemeType       { return v.typ }
+func (v *Verifier) Header() string         { return v.header }
+func (v *Verifier) Handler() *parser.Func  { return v.handler }
+func (v *Verifier) Principal() *parser.Var { return v.principal }
+func (v *Verifier) IsBearer() bool         { return v.typ == SchemeTypeBearer }
+func (v *Verifier) IsApiKey() bool         { return v.typ == SchemeTypeApiKey }
+
+// Checks if the verifier expects required scopes as its last param.
+func (v *Verifier) WithScopes() bool { return len(v.handler.Params()) == 3 }
+
+func (s *Security) Verifier() *Verifier { return s.verifier }
+func (s *Security) Scopes() []string    { return s.scopes }
+
+// Checks if the given param should receive the authenticated principal.
+func (s *Security) isPrincipal(v *parser.Var) bool {
+	if s == nil {
+		return false
+	}
+
+	return v.TypeExpr().String() == s.verifier.principal.TypeExpr().String()
+}
+
+func parseVerifier(directive *parser.Directive, handler *parser.Func) (*Verifier, error) {
+	if handler.IsGeneric() {
+		return nil, fmt.Errorf("%w: %s", ErrGenericHandler, handler.String())
+	}
+
+	var (
+		params  = handler.Params()
+		returns = handler.Returns()
+	)
+
+	if len(params) < 2 || len(params) > 3 || len(returns) != 2 ||
+		!isType(params[0], parser.ContextTypeName) ||
+		!isType(params[1], "string") || params[1].IsPointer() ||
+		(len(params) == 3 && (!isType(params[2], "string") || !params[2].IsSlice())) ||
+		returns[0].Type() == nil || !isType(returns[1], parser.ErrorTypeName) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidVerifier, handler.String())
+	}
+
+	verifier := &Verifier{
+		scheme:    directive.Value(schemeDirectiveParam),
+		typ:       SchemeType(directive.Value(typeDirectiveParam)),
+		header:    directive.Value(headerDirectiveParam),
+		handler:   handler,
+		principal: returns[0],
+	}
+
+	if verifier.scheme == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidScheme, handler.String())
+	}
+
+	switch verifier.typ {
+	case "":
+		verifier.typ = SchemeTypeBearer
+	case SchemeTypeBearer:
+	case SchemeTypeApiKey:
+		if verifier.header == "" {
+			verifier.header = defaultApiKeyHeader
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidScheme, verifier.typ)
+	}
+
+	return verifier, nil
+}
+
+func parseSecurity(directive *parser.Directive, verifiers map[string]*Verifier) (*Security, error) {
+	scheme := directive.Value(schemeDirectiveParam)
+	verifier, found := verifiers[scheme]
+
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownScheme, scheme)
+	}
+
+	security := &Security{
+		verifier: verifier,
+		scopes:   directive.List(scopesDirectiveParam),
+	}
+
+	// Endpoints would be reachable without the required scopes
+	if len(security.scopes) > 0 && !verifier.WithScopes() {
+		return nil, &directiveError{directive, fmt.Errorf("%w: %s", ErrUnverifiedScopes, verifier.handler.String())}
+	}
+
+	return security, nil
+}
+
+func isType(v *parser.Var, name string) bool {
+	return v.Type() != nil && v.Type().String() == name
+}
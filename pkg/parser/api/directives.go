@@ -0,0 +1,70 @@
+package api
+
+import "github.com/YuukanOO/ease/pkg/parser"
+
+var (
+	useParam = &parser.ParamSchema{Name: useDirectiveParam, Type: parser.ValueTypeList, Doc: "Names of middlewares to apply"}
+	tagParam = &parser.ParamSchema{Name: tagDirectiveParam, Type: parser.ValueTypeList, Doc: "Tags used to categorize endpoints"}
+
+	directives = []*parser.DirectiveSchema{
+		{
+			Name:    apiDirective,
+			Doc:     "Exposes the function as an HTTP endpoint, may be repeated to declare aliases",
+			Targets: parser.DeclKindFunc,
+			Params: []*parser.ParamSchema{
+				{Name: methodDirectiveParam, Doc: "HTTP method, defaults to GET"},
+				{Name: pathDirectiveParam, Doc: "Route path, relative to the group prefix if any"},
+				{Name: summaryDirectiveParam, Doc: "Short summary of the endpoint"},
+				{Name: descriptionDirectiveParam, Doc: "Description of the endpoint, defaults to the function documentation"},
+				{Name: deprecatedDirectiveParam, Type: parser.ValueTypeBool, Doc: "Marks the endpoint as deprecated"},
+				tagParam,
+				useParam,
+			},
+		},
+		{
+			Name:    groupDirective,
+			Doc:     "Groups endpoints of a package or of every method of a type",
+			Targets: parser.DeclKindPackage | parser.DeclKindType,
+			Params: []*parser.ParamSchema{
+				{Name: prefixDirectiveParam, Doc: "Path prefix of every endpoint in the group"},
+				tagParam,
+				useParam,
+			},
+		},
+		{
+			Name:    middlewareDirective,
+			Doc:     "Declares a reusable middleware with the signature func(..., http.Handler) http.Handler",
+			Targets: parser.DeclKindFunc,
+			Params: []*parser.ParamSchema{
+				{Name: nameDirectiveParam, Doc: "Name used to reference the middleware, defaults to the function name"},
+			},
+		},
+		{
+			Name:    useDirective,
+			Doc:     "Applies middlewares to every endpoint of the package",
+			Targets: parser.DeclKindPackage,
+			Args:    "Names of middlewares to apply",
+		},
+		{
+			Name:    verifierDirective,
+			Doc:     "Declares the function used to verify credentials of a security scheme",
+			Targets: parser.DeclKindFunc,
+			Params: []*parser.ParamSchema{
+				{Name: schemeDirectiveParam, Doc: "Name of the security scheme"},
+				{Name: typeDirectiveParam, Doc: "Type of the scheme (bearer or apikey), defaults to bearer"},
+				{Name: headerDirectiveParam, Doc: "Header containing the api key, defaults to X-API-Key"},
+			},
+		},
+		{
+			Name:    authDirective,
+			Doc:     "Requires the endpoint caller to be authenticated",
+			Targets: parser.DeclKindFunc,
+			Params: []*parser.ParamSchema{
+				{Name: schemeDirectiveParam, Doc: "Name of the security scheme to use"},
+				{Name: scopesDirectiveParam, Type: parser.ValueTypeList, Doc: "Scopes required to access the endpoint"},
+			},
+		},
+	}
+)
+
+func (p *apiParser) Directives() []*parser.DirectiveSchema { return directives }
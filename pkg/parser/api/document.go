@@ -0,0 +1,182 @@
+package api
+
+import "github.com/YuukanOO/ease/pkg/parser"
+
+// Key of the API in the inspected document extensions.
+const documentKey = "api"
+
+var paramFromNames = map[ParamFrom]string{
+	FromSource: "source",
+	FromPath:   "path",
+	FromQuery:  "query",
+	FromBody:   "body",
+	FromAuth:   "auth",
+}
+
+type (
+	// Serializable representation of the API schema, part of the parser.Document.
+	Document struct {
+		Title       string           `json:"title,omitempty"`
+		Description string           `json:"description,omitempty"`
+		Endpoints   []*DocEndpoint   `json:"endpoints"`
+		Middlewares []*DocMiddleware `json:"middlewares"`
+		Verifiers   []*DocVerifier   `json:"verifiers"`
+		Groups      []*DocGroup      `json:"groups"`
+	}
+
+	DocEndpoint struct {
+		ID          string          `json:"id"`
+		Method      Method          `json:"method"`
+		Path        string          `json:"path"`
+		Handler     string          `json:"handler"` // ID of the handler func
+		Group       string          `json:"group,omitempty"`
+		Summary     string          `json:"summary,omitempty"`
+		Description string          `json:"description,omitempty"`
+		Deprecated  bool            `json:"deprecated,omitempty"`
+		Tags        []string        `json:"tags,omitempty"`
+		Security    *DocSecurity    `json:"security,omitempty"`
+		Params      []*DocParam     `json:"params"`
+		Returns     *parser.DocExpr `json:"returns,omitempty"`
+		Middlewares []string        `json:"middlewares,omitempty"` // Names of middlewares applied by the endpoint itself
+	}
+
+	DocParam struct {
+		Name string          `json:"name"`
+		From string          `json:"from"`
+		Type *parser.DocExpr `json:"type"`
+	}
+
+	DocSecurity struct {
+		Scheme string   `json:"scheme"`
+		Scopes []string `json:"scopes,omitempty"`
+	}
+
+	DocMiddleware struct {
+		Name    string `json:"name"`
+		Handler string `json:"handler"`
+	}
+
+	DocVerifier struct {
+		Scheme    string          `json:"scheme"`
+		Type      SchemeType      `json:"type"`
+		Header    string          `json:"header,omitempty"`
+		Handler   string          `json:"handler"`
+		Principal *parser.DocExpr `json:"principal"`
+	}
+
+	DocGroup struct {
+		Key         string   `json:"key"`
+		Prefix      string   `json:"prefix,omitempty"`
+		Path        string   `json:"path,omitempty"`
+		Parent      string   `json:"parent,omitempty"`
+		Tags        []string `json:"tags,omitempty"`
+		Middlewares []string `json:"middlewares,omitempty"`
+	}
+)
+
+func (f ParamFrom) String() string { return paramFromNames[f] }
+
+func (p *apiParser) Inspect() (string, any) { return documentKey, p.schema.Document() }
+
+// Document builds the serializable representation of the schema.
+func (s *API) Document() *Document {
+	doc := &Document{
+		Title:       s.title,
+		Description: s.description,
+		Endpoints:   make([]*DocEndpoint, len(s.endpoints)),
+		Middlewares: make([]*DocMiddleware, len(s.middlewares)),
+		Verifiers:   make([]*DocVerifier, len(s.verifiers)),
+		Groups:      make([]*DocGroup, len(s.groups)),
+	}
+
+	for i, endpoint := range s.endpoints {
+		doc.Endpoints[i] = endpoint.document()
+	}
+
+	for i, middleware := range s.middlewares {
+		doc.Middlewares[i] = &DocMiddleware{
+			Name:    middleware.name,
+			Handler: middleware.handler.ID(),
+		}
+	}
+
+	for i, verifier := range s.verifiers {
+		doc.Verifiers[i] = &DocVerifier{
+			Scheme:    verifier.scheme,
+			Type:      verifier.typ,
+			Header:    verifier.header,
+			Handler:   verifier.handler.ID(),
+			Principal: parser.DescribeExpr(verifier.principal.TypeExpr()),
+		}
+	}
+
+	for i, group := range s.groups {
+		doc.Groups[i] = &DocGroup{
+			Key:         group.key,
+			Prefix:      group.prefix,
+			Path:        group.Path(),
+			Tags:        group.tags,
+			Middlewares: middlewareNames(group.middlewares),
+		}
+
+		if group.parent != nil {
+			doc.Groups[i].Parent = group.parent.key
+		}
+	}
+
+	return doc
+}
+
+func (e *Endpoint) document() *DocEndpoint {
+	doc := &DocEndpoint{
+		ID:          e.ID(),
+		Method:      e.method,
+		Path:        e.path,
+		Handler:     e.handler.ID(),
+		Summary:     e.summary,
+		Description: e.description,
+		Deprecated:  e.deprecated,
+		Tags:        e.tags,
+		Params:      make([]*DocParam, len(e.params)),
+		Middlewares: middlewareNames(e.middlewares),
+	}
+
+	if e.group != nil {
+		doc.Group = e.group.key
+	}
+
+	if e.security != nil {
+		doc.Security = &DocSecurity{
+			Scheme: e.security.verifier.scheme,
+			Scopes: e.security.scopes,
+		}
+	}
+
+	if e.returns != nil {
+		doc.Returns = parser.DescribeExpr(e.returns.TypeExpr())
+	}
+
+	for i, param := range e.params {
+		doc.Params[i] = &DocParam{
+			Name: param.name,
+			From: param.src.String(),
+			Type: parser.DescribeExpr(param.decl.TypeExpr()),
+		}
+	}
+
+	return doc
+}
+
+func middlewareNames(middlewares []*Middleware) []string {
+	if len(middlewares) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(middlewares))
+
+	for i, m := range middlewares {
+		names[i] = m.name
+	}
+
+	return names
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"path"
+	"strings"
+
+	"github.com/YuukanOO/ease/pkg/collection"
+	"github.com/YuukanOO/ease/pkg/parser"
+)
+
+const (
+	prefixDirectiveParam = "prefix"
+	tagDirectiveParam    = "tag"
+)
+
+// Represents a set of endpoints sharing a path prefix, tags and middlewares. Groups are
+// declared on a package or a type (the handlers receiver) and may be nested.
+type Group struct {
+	key         string
+	prefix      string
+	tags        []string
+	middlewares []*Middleware
+	parent      *Group
+}
+
+func (g *Group) Key() string                { return g.key }
+func (g *Group) Prefix() string             { return g.prefix }
+func (g *Group) Tags() []string             { return g.tags }
+func (g *Group) Middlewares() []*Middleware { return g.middlewares }
+func (g *Group) Parent() *Group             { return g.parent }
+
+// Path returns the full prefix of this group, including its parents ones.
+func (g *Group) Path() string {
+	if g == nil {
+		return ""
+	}
+
+	return joinPaths(g.parent.Path(), g.prefix)
+}
+
+// Checks if the middleware with the given name is applied by this group or one of its parents.
+func (g *Group) uses(name string) bool {
+	for current := g; current != nil; current = current.parent {
+		for _, m := range current.middlewares {
+			if m.name == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Returns all tags of this group and its parents, outermost first.
+func (g *Group) allTags() []string {
+	if g == nil {
+		return nil
+	}
+
+	return append(g.parent.allTags(), g.tags...)
+}
+
+func parseGroup(key string, directive *parser.Directive, parent *Group, middlewares map[string]*Middleware) (*Group, error) {
+	group := &Group{
+		key:    key,
+		parent: parent,
+	}
+
+	if directive == nil {
+		return group, nil
+	}
+
+	group.prefix = directive.Value(prefixDirectiveParam)
+	group.tags = directive.List(tagDirectiveParam)
+
+	uses := collection.NewSet[*Middleware]()
+
+	if err := useMiddlewares(uses, middlewares, parent, directive.List(useDirectiveParam)...); err != nil {
+		return nil, err
+	}
+
+	group.middlewares = uses.Items()
+
+	return group, nil
+}
+
+// Joins two route paths, keeping the trailing slash of the last one if any.
+func joinPaths(base, relative string) string {
+	if relative == "" {
+		return base
+	}
+
+	joined := path.Join(base, relative)
+
+	if strings.HasSuffix(relative, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+
+	return joined
+}
@@ -7,27 +7,69 @@ import (
 )
 
 var (
-	ErrInvalidPath   = errors.New("invalid API path")
-	ErrInvalidMethod = errors.New("invalid HTTP method")
+	ErrInvalidPath       = errors.New("invalid API path")
+	ErrInvalidMethod     = errors.New("invalid HTTP method")
+	ErrInvalidMiddleware = errors.New("invalid middleware signature, expected func(..., http.Handler) http.Handler")
+	ErrUnknownMiddleware = errors.New("unknown middleware")
+	ErrInvalidVerifier   = errors.New("invalid verifier signature, expected func(context.Context, string[, []string]) (P, error)")
+	ErrInvalidScheme     = errors.New("invalid security scheme")
+	ErrUnknownScheme     = errors.New("unknown security scheme")
+	ErrUnverifiedScopes  = errors.New("scopes can not be verified, the verifier has no []string param to receive them")
+	ErrUnsupportedParam  = errors.New("unsupported handler param type")
+	ErrUnsupportedReturn = errors.New("unsupported handler return type")
+	ErrGenericHandler    = errors.New("generic functions can not be used as handlers, middlewares or verifiers")
 )
 
 type (
 	Extension interface {
 		parser.Extension
+		parser.DirectivesProvider
 		Schema() *API
 	}
 
+	Option func(*apiParser)
+
 	apiParser struct {
-		schema *API
+		schema      *API
+		mounts      map[string]string // Package path to prefix overrides
+		middlewares map[string]*Middleware
+		verifiers   map[string]*Verifier // Verifiers by scheme name
+		groups      map[string]*Group
 	}
 )
 
-const apiDirective = "api"
+const (
+	apiDirective        = "api"
+	middlewareDirective = "middleware"
+	useDirective        = "use"
+	groupDirective      = "group"
+	verifierDirective   = "verifier"
+	authDirective       = "auth"
+)
 
 // Builds a new API parser to process files and extract an API schema.
-func New() Extension {
-	return &apiParser{
-		schema: &API{},
+func New(opts ...Option) Extension {
+	p := &apiParser{
+		schema:      &API{},
+		mounts:      make(map[string]string),
+		middlewares: make(map[string]*Middleware),
+		verifiers:   make(map[string]*Verifier),
+		groups:      make(map[string]*Group),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// WithMount mounts endpoints of the given package under the given prefix, overriding
+// the one declared by the package itself if any. This is mostly useful to integrate
+// an external module.
+func WithMount(packagePath, prefix string) Option {
+	return func(p *apiParser) {
+		p.mounts[packagePath] = prefix
 	}
 }
 
@@ -35,25 +77,152 @@ func New() Extension {
 func (p *apiParser) Schema() *API { return p.schema }
 
 func (p *apiParser) Visit(result parser.Result) error {
+	diagnostics := result.Diagnostics()
+
+	p.reset()
+
+	// Middlewares and verifiers must be known before parsing endpoints since they may reference them
 	for _, fn := range result.Funcs() {
 		if !fn.IsExported() {
 			continue
 		}
 
-		api, hasApiDirective := fn.Directive(apiDirective)
+		if directive, hasMiddlewareDirective := fn.Directive(middlewareDirective); hasMiddlewareDirective {
+			middleware, err := parseMiddleware(directive, fn)
 
-		if !hasApiDirective {
-			continue
+			if err != nil {
+				diagnostics.Add(newDiagnostic(fn, err))
+			} else {
+				p.middlewares[middleware.name] = middleware
+				p.schema.middlewares = append(p.schema.middlewares, middleware)
+			}
 		}
 
-		endpoint, err := parseEndpoint(api, fn)
+		if directive, hasVerifierDirective := fn.Directive(verifierDirective); hasVerifierDirective {
+			verifier, err := parseVerifier(directive, fn)
+
+			if err != nil {
+				diagnostics.Add(newDiagnostic(fn, err))
+			} else {
+				p.verifiers[verifier.scheme] = verifier
+				p.schema.verifiers = append(p.schema.verifiers, verifier)
+			}
+		}
+	}
 
-		if err != nil {
-			return err
+	for _, fn := range result.Funcs() {
+		if !fn.IsExported() {
+			continue
 		}
 
-		p.schema.endpoints = append(p.schema.endpoints, endpoint)
+		// Each occurrence of the directive declares a distinct endpoint (aliases, versioned paths, ...)
+		for _, api := range fn.Directives(apiDirective) {
+			endpoint, err := p.parseEndpoint(api, fn)
+
+			if err != nil {
+				diagnostics.Add(newDiagnostic(fn, err))
+				continue
+			}
+
+			p.schema.endpoints = append(p.schema.endpoints, endpoint)
+		}
 	}
 
+	diagnostics.Add(p.schema.validate()...)
+
 	return nil
 }
+
+// Forgets what a previous visit extracted so the parser can be used for multiple parses,
+// as in watch mode. The schema is cleared in place since generators reference it.
+func (p *apiParser) reset() {
+	*p.schema = API{}
+	p.middlewares = make(map[string]*Middleware)
+	p.verifiers = make(map[string]*Verifier)
+	p.groups = make(map[string]*Group)
+}
+
+func (p *apiParser) parseEndpoint(directive *parser.Directive, fn *parser.Func) (*Endpoint, error) {
+	group, err := p.handlerGroup(fn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var security *Security
+
+	if auth, hasAuthDirective := fn.Directive(authDirective); hasAuthDirective {
+		if security, err = parseSecurity(auth, p.verifiers); err != nil {
+			return nil, err
+		}
+	}
+
+	return parseEndpoint(directive, fn, group, security, p.middlewares)
+}
+
+// Retrieve the group of the given handler, being the one declared on its receiver
+// type or else on its package. Returns nil if the handler does not belong to any group.
+func (p *apiParser) handlerGroup(fn *parser.Func) (*Group, error) {
+	pkgGroup, err := p.packageGroup(fn.Package())
+
+	if err != nil || fn.Recv() == nil {
+		return pkgGroup, err
+	}
+
+	typ := fn.Recv().Type()
+
+	if typ == nil {
+		return pkgGroup, nil
+	}
+
+	directive, found := typ.Directive(groupDirective)
+
+	if !found {
+		return pkgGroup, nil
+	}
+
+	return p.group(typ.String(), directive, pkgGroup)
+}
+
+func (p *apiParser) packageGroup(pkg *parser.Package) (*Group, error) {
+	if pkg == nil {
+		return nil, nil
+	}
+
+	directive, found := pkg.Directive(groupDirective)
+	prefix, mounted := p.mounts[pkg.Path()]
+
+	if !found && !mounted {
+		return nil, nil
+	}
+
+	group, err := p.group(pkg.Path(), directive, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if mounted {
+		group.prefix = prefix
+	}
+
+	return group, nil
+}
+
+// Retrieve the group with the given key or parse it if not already done.
+func (p *apiParser) group(key string, directive *parser.Directive, parent *Group) (*Group, error) {
+	if group, found := p.groups[key]; found {
+		return group, nil
+	}
+
+	group, err := parseGroup(key, directive, parent, p.middlewares)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.groups[key] = group
+	p.schema.groups = append(p.schema.groups, group)
+
+	return group, nil
+}
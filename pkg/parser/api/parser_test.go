@@ -0,0 +1,291 @@
+package api_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/YuukanOO/ease/pkg/parser"
+	"github.com/YuukanOO/ease/pkg/parser/api"
+)
+
+func TestSecurity(t *testing.T) {
+	extension := api.New()
+	result, err := parser.New(extension).Parse("github.com/YuukanOO/ease/pkg/parser/api/testdata/auth")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("should reject scopes the verifier can not receive", func(t *testing.T) {
+		diagnostics := result.Diagnostics().Items()
+
+		if len(diagnostics) != 1 {
+			t.Fatalf("expected 1 diagnostic, got %d: %v", len(diagnostics), diagnostics)
+		}
+
+		if d := diagnostics[0]; d.Code != "unverified-scopes" || filepath.Base(d.Position.Filename) != "auth.go" || d.Position.Line != 20 {
+			t.Errorf("expected unverified-scopes at the auth directive auth.go:20, got %v", d)
+		}
+	})
+
+	t.Run("should pass scopes to verifiers accepting them", func(t *testing.T) {
+		scopes := make(map[string][]string)
+
+		for _, endpoint := range extension.Schema().Endpoints() {
+			scopes[endpoint.Handler().Name()] = endpoint.Security().Scopes()
+		}
+
+		if len(scopes) != 2 || len(scopes["Me"]) != 0 || len(scopes["ScopedAdmin"]) != 1 || scopes["ScopedAdmin"][0] != "admin" {
+			t.Errorf("expected Me without scopes and ScopedAdmin requiring admin, got %v", scopes)
+		}
+	})
+}
+
+func TestRoutes(t *testing.T) {
+	result, err := parser.New(api.New()).Parse("github.com/YuukanOO/ease/pkg/parser/api/testdata/routes")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Diagnostics by handler line in routes.go
+	reported := make(map[int]string)
+
+	for _, d := range result.Diagnostics().Items() {
+		if filepath.Base(d.Position.Filename) != "routes.go" {
+			t.Errorf("expected diagnostics to be located in routes.go, got %v", d)
+		}
+
+		related := 0
+
+		if len(d.Related) > 0 {
+			related = d.Related[0].Position.Line
+		}
+
+		reported[d.Position.Line] = fmt.Sprintf("%s %d", d.Code, related)
+	}
+
+	for _, test := range []struct {
+		name     string
+		line     int
+		expected string // Code and line of the related position if any, empty if nothing is reported
+	}{
+		{"first route", 6, ""},
+		{"duplicate route", 9, "duplicate-route 6"},
+		{"duplicate route with other wildcard names", 15, "duplicate-route 12"},
+		{"path param without handler param", 18, "missing-path-param 0"},
+		{"second path param without handler param", 21, "missing-path-param 0"},
+		{"invalid method", 24, "invalid-method 0"},
+		{"raw endpoint reading path params itself", 27, ""},
+		{"same path with another method", 30, ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := reported[test.line]; got != test.expected {
+				t.Errorf("expected %q at routes.go:%d, got %q", test.expected, test.line, got)
+			}
+		})
+	}
+
+	if diagnostics := result.Diagnostics().Items(); len(diagnostics) != 5 {
+		t.Errorf("expected 5 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+}
+
+func TestMiddlewares(t *testing.T) {
+	extension := api.New()
+	result, err := parser.New(extension).Parse("github.com/YuukanOO/ease/pkg/parser/api/testdata/middlewares")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("should declare middlewares with a valid signature only", func(t *testing.T) {
+		var names []string
+
+		for _, middleware := range extension.Schema().Middlewares() {
+			names = append(names, middleware.Name())
+		}
+
+		if got := strings.Join(names, ","); got != "logging,auth,audit" {
+			t.Errorf("expected logging, auth and audit middlewares, got %s", got)
+		}
+	})
+
+	t.Run("should resolve middlewares dependencies", func(t *testing.T) {
+		var types []*parser.TypeExpr
+
+		for _, middleware := range extension.Schema().Middlewares() {
+			for _, dep := range middleware.Dependencies() {
+				types = append(types, dep.TypeExpr())
+			}
+		}
+
+		resolved, err := result.Funcs().Resolve(types...)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if deps := resolved.Dependencies(); len(types) != 1 || len(deps) != 1 || deps[0].Name() != "NewLogger" {
+			t.Errorf("expected the logger to be built by NewLogger, got %v", deps)
+		}
+	})
+
+	for _, test := range []struct {
+		name     string
+		handler  string
+		expected string // Names of middlewares applied to the endpoint
+	}{
+		{"package middlewares first, then endpoint ones without duplicates", "List", "logging,audit,auth"},
+		{"package middlewares only", "Health", "logging"},
+	} {
+		t.Run("should apply "+test.name, func(t *testing.T) {
+			for _, endpoint := range extension.Schema().Endpoints() {
+				if endpoint.Handler().Name() != test.handler {
+					continue
+				}
+
+				var names []string
+
+				for _, middleware := range endpoint.Middlewares() {
+					names = append(names, middleware.Name())
+				}
+
+				if got := strings.Join(names, ","); got != test.expected {
+					t.Errorf("expected %s to use %s, got %s", test.handler, test.expected, got)
+				}
+
+				return
+			}
+
+			t.Errorf("expected an endpoint for %s", test.handler)
+		})
+	}
+
+	t.Run("should report invalid and unknown middlewares", func(t *testing.T) {
+		var reported []string
+
+		for _, d := range result.Diagnostics().Items() {
+			reported = append(reported, fmt.Sprintf("%s:%d %s", filepath.Base(d.Position.Filename), d.Position.Line, d.Code))
+		}
+
+		expected := []string{
+			"middlewares.go:20 invalid-middleware",
+			"middlewares.go:23 invalid-middleware",
+			"middlewares.go:29 unknown-middleware",
+		}
+
+		if !reflect.DeepEqual(reported, expected) {
+			t.Errorf("expected %v, got %v", expected, reported)
+		}
+	})
+}
+
+func TestGroups(t *testing.T) {
+	const pkg = "github.com/YuukanOO/ease/pkg/parser/api/testdata/groups"
+
+	names := func(middlewares []*api.Middleware) string {
+		var result []string
+
+		for _, middleware := range middlewares {
+			result = append(result, middleware.Name())
+		}
+
+		return strings.Join(result, ",")
+	}
+
+	for _, test := range []struct {
+		name      string
+		opts      []api.Option
+		endpoints []string // Handler, path, tags, middlewares and group key of endpoints
+		groups    []string // Key, path and middlewares of groups
+	}{
+		{"should prefix paths and inherit tags and middlewares of nested groups", nil, []string{
+			"Get /api/todos/:id api,todos,read  " + pkg + ".Todos",
+			"Create /api/todos api,todos  " + pkg + ".Todos",
+			"Health /api/health api  " + pkg,
+		}, []string{
+			pkg + " /api logging",
+			pkg + ".Todos /api/todos audit",
+		}},
+		{"should mount a package under another prefix", []api.Option{api.WithMount(pkg, "/v2")}, []string{
+			"Get /v2/todos/:id api,todos,read  " + pkg + ".Todos",
+			"Create /v2/todos api,todos  " + pkg + ".Todos",
+			"Health /v2/health api  " + pkg,
+		}, []string{
+			pkg + " /v2 logging",
+			pkg + ".Todos /v2/todos audit",
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			extension := api.New(test.opts...)
+			result, err := parser.New(extension).Parse(pkg)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diagnostics := result.Diagnostics().Items(); len(diagnostics) != 0 {
+				t.Fatalf("expected no diagnostic, got %v", diagnostics)
+			}
+
+			var endpoints []string
+
+			for _, e := range extension.Schema().Endpoints() {
+				endpoints = append(endpoints, fmt.Sprintf("%s %s %s %s %s",
+					e.Handler().Name(), e.Path(), strings.Join(e.Tags(), ","), names(e.Middlewares()), e.Group().Key()))
+			}
+
+			if !reflect.DeepEqual(endpoints, test.endpoints) {
+				t.Errorf("expected endpoints\n%v\ngot\n%v", test.endpoints, endpoints)
+			}
+
+			var groups []string
+
+			for _, g := range extension.Schema().Groups() {
+				groups = append(groups, fmt.Sprintf("%s %s %s", g.Key(), g.Path(), names(g.Middlewares())))
+			}
+
+			if !reflect.DeepEqual(groups, test.groups) {
+				t.Errorf("expected groups %v, got %v", test.groups, groups)
+			}
+		})
+	}
+}
+
+func TestDirectiveOccurrences(t *testing.T) {
+	extension := api.New()
+	result, err := parser.New(extension).Parse("github.com/YuukanOO/ease/pkg/parser/api/testdata/occurrences")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diagnostics := result.Diagnostics().Items(); len(diagnostics) != 0 {
+		t.Fatalf("expected no diagnostic, got %v", diagnostics)
+	}
+
+	t.Run("should declare an endpoint per api directive and apply every package use directive", func(t *testing.T) {
+		var endpoints []string
+
+		for _, e := range extension.Schema().Endpoints() {
+			var names []string
+
+			for _, middleware := range e.Middlewares() {
+				names = append(names, middleware.Name())
+			}
+
+			endpoints = append(endpoints, fmt.Sprintf("%s %s %s", e, e.Handler().Name(), strings.Join(names, ",")))
+		}
+
+		if expected := []string{
+			"GET /todos List logging,audit",
+			"POST /v2/todos List logging,audit",
+		}; !reflect.DeepEqual(endpoints, expected) {
+			t.Errorf("expected endpoints %v, got %v", expected, endpoints)
+		}
+	})
+}
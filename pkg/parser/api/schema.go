@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/YuukanOO/ease/pkg/collection"
 	"github.com/YuukanOO/ease/pkg/parser"
 )
 
@@ -12,6 +13,7 @@ const (
 	FromPath                    // Params is extracted from the route path (:id for example)
 	FromQuery                   // Params is extracted from the query string
 	FromBody                    // Params is extracted from the request body
+	FromAuth                    // Params is the authenticated principal returned by the security verifier
 
 	MethodOptions Method = "OPTIONS"
 	MethodGet     Method = "GET"
@@ -22,10 +24,16 @@ const (
 )
 
 const (
-	methodDirectiveParam = "method"
-	pathDirectiveParam   = "path"
-	rawHttpWriter        = "net/http.ResponseWriter"
-	rawHttpRequest       = "net/http.Request"
+	methodDirectiveParam      = "method"
+	pathDirectiveParam        = "path"
+	useDirectiveParam         = "use"
+	nameDirectiveParam        = "name"
+	summaryDirectiveParam     = "summary"
+	descriptionDirectiveParam = "description"
+	deprecatedDirectiveParam  = "deprecated"
+	rawHttpWriter             = "net/http.ResponseWriter"
+	rawHttpRequest            = "net/http.Request"
+	rawHttpHandler            = "net/http.Handler"
 )
 
 type (
@@ -33,15 +41,33 @@ type (
 		title       string
 		description string
 		endpoints   []*Endpoint
+		middlewares []*Middleware
+		verifiers   []*Verifier
+		groups      []*Group
 	}
 
 	// Represents a single endpoint parsed from the API directive and function declaration.
 	Endpoint struct {
-		handler *parser.Func // Endpoint handler function
-		method  Method
-		path    string
-		params  []*Param
-		returns *parser.Var
+		handler      *parser.Func // Endpoint handler function
+		summary      string
+		description  string // Description of the endpoint, defaults to the handler documentation
+		deprecated   bool
+		method       Method
+		path         string // Full path of the endpoint, including the group prefix
+		relativePath string // Path relative to the group
+		group        *Group
+		security     *Security
+		tags         []string
+		params       []*Param
+		returns      *parser.Var
+		middlewares  []*Middleware
+	}
+
+	// Represents a reusable middleware declared with the middleware directive. The handler
+	// last param and return value are both an http.Handler, other params are dependencies.
+	Middleware struct {
+		name    string
+		handler *parser.Func
 	}
 
 	Param struct {
@@ -58,13 +84,47 @@ func (s *API) Title() string          { return s.title }
 func (s *API) Description() string    { return s.description }
 func (s *API) Endpoints() []*Endpoint { return s.endpoints }
 
+// Middlewares returns every declared middleware, used or not.
+func (s *API) Middlewares() []*Middleware { return s.middlewares }
+
+// Verifiers returns every declared security verifier.
+func (s *API) Verifiers() []*Verifier { return s.verifiers }
+
+// Groups returns every group used by at least one endpoint, parents always come first.
+func (s *API) Groups() []*Group { return s.groups }
+
 func (e *Endpoint) String() string        { return fmt.Sprintf("%s %s", e.method, e.path) }
 func (e *Endpoint) Handler() *parser.Func { return e.handler }
+func (e *Endpoint) Summary() string       { return e.summary }
+func (e *Endpoint) Description() string   { return e.description }
+func (e *Endpoint) Deprecated() bool      { return e.deprecated }
 func (e *Endpoint) Method() Method        { return e.method }
 func (e *Endpoint) Path() string          { return e.path }
+func (e *Endpoint) RelativePath() string  { return e.relativePath }
+func (e *Endpoint) Group() *Group         { return e.group }
+func (e *Endpoint) Tags() []string        { return e.tags }
+func (e *Endpoint) Security() *Security   { return e.security }
 func (e *Endpoint) Params() []*Param      { return e.params }
 func (e *Endpoint) Returns() *parser.Var  { return e.returns }
 
+// ID uniquely identifies the endpoint since a handler may be exposed by multiple endpoints.
+func (e *Endpoint) ID() string { return fmt.Sprintf("%s %s", e.handler, e) }
+
+// Principal returns the param receiving the authenticated principal if any.
+func (e *Endpoint) Principal() *Param {
+	for _, p := range e.params {
+		if p.FromAuth() {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// Middlewares returns middlewares applied to this endpoint only, without the ones
+// applied by its group.
+func (e *Endpoint) Middlewares() []*Middleware { return e.middlewares }
+
 func (e *Endpoint) IsRaw() bool {
 	p := e.Params()
 
@@ -72,7 +132,17 @@ func (e *Endpoint) IsRaw() bool {
 		return false
 	}
 
-	return p[0].decl.Type().String() == rawHttpWriter && p[1].decl.Type().String() == rawHttpRequest
+	return isType(p[0].decl, rawHttpWriter) && isType(p[1].decl, rawHttpRequest)
+}
+
+func (m *Middleware) Name() string          { return m.name }
+func (m *Middleware) Handler() *parser.Func { return m.handler }
+
+// Dependencies returns handler params which should be resolved and given to the
+// middleware, that is every param but the last one which is the next http.Handler.
+func (m *Middleware) Dependencies() parser.Vars {
+	params := m.handler.Params()
+	return params[:len(params)-1]
 }
 
 func (p *Param) Name() string      { return p.name }
@@ -82,37 +152,74 @@ func (p *Param) FromSource() bool  { return p.src == FromSource }
 func (p *Param) FromPath() bool    { return p.src == FromPath }
 func (p *Param) FromQuery() bool   { return p.src == FromQuery }
 func (p *Param) FromBody() bool    { return p.src == FromBody }
+func (p *Param) FromAuth() bool    { return p.src == FromAuth }
 
-func parseEndpoint(directive *parser.Directive, handler *parser.Func) (*Endpoint, error) {
-	endpoint := &Endpoint{}
-
-	for name, value := range directive.Params {
-		switch name {
-		case methodDirectiveParam:
-			m, err := parseMethod(value)
+func parseEndpoint(directive *parser.Directive, handler *parser.Func, group *Group, security *Security, middlewares map[string]*Middleware) (*Endpoint, error) {
+	// Generators have no way to know which instantiation should be called
+	if handler.IsGeneric() {
+		return nil, fmt.Errorf("%w: %s", ErrGenericHandler, handler.String())
+	}
 
-			if err != nil {
-				return nil, err
-			}
+	endpoint := &Endpoint{
+		group:    group,
+		security: security,
+		tags:     group.allTags(),
+	}
+	uses := collection.NewSet[*Middleware]()
 
-			endpoint.method = m
-		case pathDirectiveParam:
-			endpoint.path = value
+	// Package wide middlewares are applied first, in the order of their directives
+	for _, use := range handler.Package().Directives(useDirective) {
+		if err := useMiddlewares(uses, middlewares, group, use.Args...); err != nil {
+			return nil, err
 		}
 	}
 
-	if endpoint.method == "" {
-		endpoint.method = MethodGet
+	if err := useMiddlewares(uses, middlewares, group, directive.List(useDirectiveParam)...); err != nil {
+		return nil, err
+	}
+
+	method, err := parseMethod(directive.Value(methodDirectiveParam))
+
+	if err != nil {
+		return nil, err
 	}
 
+	deprecated, err := directive.Bool(deprecatedDirectiveParam)
+
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.method = method
+	endpoint.deprecated = deprecated
+	endpoint.relativePath = directive.Value(pathDirectiveParam)
+	endpoint.summary = directive.Value(summaryDirectiveParam)
+	endpoint.description = directive.Value(descriptionDirectiveParam)
+	endpoint.tags = append(endpoint.tags, directive.List(tagDirectiveParam)...)
+
+	// Fallback to the handler documentation
+	if endpoint.description == "" {
+		endpoint.description = strings.TrimSpace(handler.Doc())
+	}
+
+	// The path may only be omitted when the group has a prefix
+	endpoint.path = joinPaths(group.Path(), endpoint.relativePath)
+
 	if endpoint.path == "" {
 		return nil, ErrInvalidPath
 	}
 
 	endpoint.handler = handler
+	endpoint.middlewares = uses.Items()
 	endpoint.params = make([]*Param, len(endpoint.handler.Params()))
+	pathParams := PathParams(endpoint.path)
 
 	for i, param := range endpoint.handler.Params() {
+		// Generators could not bind request data to those
+		if param.IsUnsupported() || param.IsVariadic() {
+			return nil, fmt.Errorf("%w: %s %s", ErrUnsupportedParam, param.Name(), param.Expr())
+		}
+
 		endpointParam := &Param{
 			name: param.Name(),
 			decl: param,
@@ -125,8 +232,9 @@ func parseEndpoint(directive *parser.Directive, handler *parser.Func) (*Endpoint
 		}
 
 		// Determine the origin of a parameter by checking if its name match a path parameter
-		// FIXME: maybe we could use a better way to check it
-		if strings.Contains(endpoint.path, ":"+param.Name()) {
+		if security.isPrincipal(param) {
+			endpointParam.src = FromAuth
+		} else if isPathParam(pathParams, param.Name()) {
 			endpointParam.src = FromPath
 		} else if endpoint.method == MethodGet {
 			endpointParam.src = FromQuery
@@ -139,6 +247,10 @@ func parseEndpoint(directive *parser.Directive, handler *parser.Func) (*Endpoint
 
 	// Determine the return type of the handler by looking at the first non-error return value.
 	for _, ret := range endpoint.handler.Returns() {
+		if ret.IsUnsupported() {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedReturn, ret.Expr())
+		}
+
 		if ret.Type().IsError() {
 			continue
 		}
@@ -150,6 +262,68 @@ func parseEndpoint(directive *parser.Directive, handler *parser.Func) (*Endpoint
 	return endpoint, nil
 }
 
+// Adds middlewares referenced by the given names to the given set, skipping the ones
+// already applied by the given group.
+func useMiddlewares(uses *collection.Set[*Middleware], middlewares map[string]*Middleware, group *Group, names ...string) error {
+	for _, name := range names {
+		middleware, found := middlewares[name]
+
+		if !found {
+			return fmt.Errorf("%w: %s", ErrUnknownMiddleware, name)
+		}
+
+		if group.uses(name) {
+			continue
+		}
+
+		uses.Set(name, middleware)
+	}
+
+	return nil
+}
+
+func parseMiddleware(directive *parser.Directive, handler *parser.Func) (*Middleware, error) {
+	if handler.IsGeneric() {
+		return nil, fmt.Errorf("%w: %s", ErrGenericHandler, handler.String())
+	}
+
+	var (
+		params  = handler.Params()
+		returns = handler.Returns()
+	)
+
+	if len(params) == 0 || len(returns) != 1 ||
+		!isHttpHandler(params[len(params)-1]) || !isHttpHandler(returns[0]) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMiddleware, handler.String())
+	}
+
+	middleware := &Middleware{
+		name:    directive.Value(nameDirectiveParam),
+		handler: handler,
+	}
+
+	// Default to the handler name with a lowercased first letter
+	if middleware.name == "" {
+		middleware.name = strings.ToLower(handler.Name()[:1]) + handler.Name()[1:]
+	}
+
+	return middleware, nil
+}
+
+func isPathParam(pathParams []string, name string) bool {
+	for _, p := range pathParams {
+		if p == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isHttpHandler(v *parser.Var) bool {
+	return v.Type() != nil && v.Type().String() == rawHttpHandler && !v.IsPointer()
+}
+
 func parseMethod(value string) (Method, error) {
 	switch Method(value) {
 	case MethodOptions,
@@ -162,6 +336,6 @@ func parseMethod(value string) (Method, error) {
 	case "": // Default to GET if not specified.
 		return MethodGet, nil
 	default:
-		return "", ErrInvalidMethod
+		return "", fmt.Errorf("%w: %s", ErrInvalidMethod, value)
 	}
 }
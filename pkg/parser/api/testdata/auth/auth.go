@@ -0,0 +1,25 @@
+package auth
+
+import "context"
+
+type User struct{}
+
+// ease:verifier scheme=bearer
+func VerifyToken(ctx context.Context, token string) (*User, error) { return &User{}, nil }
+
+// ease:verifier scheme=scoped
+func VerifyScopedToken(ctx context.Context, token string, scopes []string) (*User, error) {
+	return &User{}, nil
+}
+
+// ease:api path=/me
+// ease:auth scheme=bearer
+func Me(user *User) *User { return user }
+
+// ease:api path=/admin
+// ease:auth scheme=bearer scopes=admin
+func Admin(user *User) *User { return user }
+
+// ease:api path=/scoped/admin
+// ease:auth scheme=scoped scopes=admin
+func ScopedAdmin(user *User) *User { return user }
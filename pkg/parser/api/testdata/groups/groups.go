@@ -0,0 +1,22 @@
+// ease:group prefix=/api tag=api use=logging
+package groups
+
+import "net/http"
+
+// ease:middleware
+func Logging(next http.Handler) http.Handler { return next }
+
+// ease:middleware
+func Audit(next http.Handler) http.Handler { return next }
+
+// ease:group prefix=todos tag=todos use=audit,logging
+type Todos struct{}
+
+// ease:api path=:id tag=read use=logging,audit
+func (t *Todos) Get(id string) {}
+
+// ease:api method=POST
+func (t *Todos) Create() {}
+
+// ease:api path=/health
+func Health() {}
@@ -0,0 +1,32 @@
+// ease:use logging
+package middlewares
+
+import "net/http"
+
+type Logger struct{}
+
+func NewLogger() *Logger { return &Logger{} }
+
+// ease:middleware
+func Logging(logger *Logger, next http.Handler) http.Handler { return next }
+
+// ease:middleware name=auth
+func Authenticate(next http.Handler) http.Handler { return next }
+
+// ease:middleware
+func Audit(next http.Handler) http.Handler { return next }
+
+// ease:middleware
+func WithoutReturn(next http.Handler) {}
+
+// ease:middleware
+func WithoutNext(logger *Logger) http.Handler { return nil }
+
+// ease:api path=/todos use=audit,auth,audit,logging
+func List() {}
+
+// ease:api path=/unknown use=auth,nope
+func Unknown() {}
+
+// ease:api path=/health
+func Health() {}
@@ -0,0 +1,15 @@
+// ease:use logging
+// ease:use audit
+package occurrences
+
+import "net/http"
+
+// ease:middleware
+func Logging(next http.Handler) http.Handler { return next }
+
+// ease:middleware
+func Audit(next http.Handler) http.Handler { return next }
+
+// ease:api path=/todos
+// ease:api path=/v2/todos method=POST
+func List() {}
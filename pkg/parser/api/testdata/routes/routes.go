@@ -0,0 +1,30 @@
+package routes
+
+import "net/http"
+
+// ease:api path=/todos
+func List() {}
+
+// ease:api path=/todos
+func Duplicate() {}
+
+// ease:api method=PUT path=/todos/:id
+func Update(id string) {}
+
+// ease:api method=PUT path=/todos/:todoID
+func UpdateByName(todoID string) {}
+
+// ease:api path=/items/:id
+func Item(itemID string) {}
+
+// ease:api path=/users/:id/posts/:post
+func UserPosts(id string) {}
+
+// ease:api method=FETCH path=/fetch
+func Fetch() {}
+
+// ease:api path=/raw/:id
+func Raw(w http.ResponseWriter, r *http.Request) {}
+
+// ease:api method=POST path=/todos
+func Create() {}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/YuukanOO/ease/pkg/diagnostic"
+	"github.com/YuukanOO/ease/pkg/parser"
+)
+
+var (
+	ErrDuplicateRoute   = errors.New("duplicate route")
+	ErrMissingPathParam = errors.New("path parameter without matching handler param")
+)
+
+// Diagnostic codes of errors reported by this package.
+var errorCodes = map[error]string{
+	ErrInvalidPath:       "invalid-path",
+	ErrInvalidMethod:     "invalid-method",
+	ErrInvalidMiddleware: "invalid-middleware",
+	ErrUnknownMiddleware: "unknown-middleware",
+	ErrInvalidVerifier:   "invalid-verifier",
+	ErrInvalidScheme:     "invalid-scheme",
+	ErrUnknownScheme:     "unknown-scheme",
+	ErrUnverifiedScopes:  "unverified-scopes",
+	ErrDuplicateRoute:    "duplicate-route",
+	ErrMissingPathParam:  "missing-path-param",
+	ErrUnsupportedParam:  "unsupported-param",
+	ErrUnsupportedReturn: "unsupported-return",
+	ErrGenericHandler:    "generic-handler",
+
+	parser.ErrDirectiveSyntax: "directive-syntax",
+}
+
+// Error caused by a directive, reported at its position instead of the function one.
+type directiveError struct {
+	directive *parser.Directive
+	err       error
+}
+
+func (e *directiveError) Error() string { return e.err.Error() }
+func (e *directiveError) Unwrap() error { return e.err }
+
+// Builds an error diagnostic located at the given function, or at the directive which
+// caused it if known.
+func newDiagnostic(fn *parser.Func, err error) *diagnostic.Diagnostic {
+	var (
+		code      = diagnostic.CodeInternal
+		pos       = fn.Position()
+		directive *directiveError
+	)
+
+	if errors.As(err, &directive) {
+		pos = directive.directive.Position
+	}
+
+	for sentinel, c := range errorCodes {
+		if errors.Is(err, sentinel) {
+			code = c
+			break
+		}
+	}
+
+	return diagnostic.Errorf(pos, code, "%s: %v", fn.Name(), err)
+}
+
+// Validates endpoints of the schema, checking for duplicate routes and path parameters
+// not matching any handler param.
+func (s *API) validate() []*diagnostic.Diagnostic {
+	var (
+		diagnostics []*diagnostic.Diagnostic
+		routes      = make(map[string]*Endpoint, len(s.endpoints))
+	)
+
+	for _, endpoint := range s.endpoints {
+		key := fmt.Sprintf("%s %s", endpoint.method, routeKey(endpoint.path))
+
+		if existing, found := routes[key]; found {
+			diagnostics = append(diagnostics,
+				newDiagnostic(endpoint.handler, fmt.Errorf("%w %s", ErrDuplicateRoute, endpoint)).
+					WithRelated(existing.handler.Position(), "previously declared here"))
+		} else {
+			routes[key] = endpoint
+		}
+
+		// Raw endpoints read path parameters themselves
+		if endpoint.IsRaw() {
+			continue
+		}
+
+		for _, name := range PathParams(endpoint.path) {
+			if !endpoint.hasPathParam(name) {
+				diagnostics = append(diagnostics, newDiagnostic(endpoint.handler, fmt.Errorf("%w: %s", ErrMissingPathParam, name)))
+			}
+		}
+	}
+
+	return diagnostics
+}
+
+func (e *Endpoint) hasPathParam(name string) bool {
+	for _, p := range e.params {
+		if p.FromPath() && p.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PathParams returns the names of every wildcard (:name or *name) in the given route path.
+func PathParams(path string) []string {
+	var params []string
+
+	for _, segment := range strings.Split(path, "/") {
+		if isWildcard(segment) {
+			params = append(params, segment[1:])
+		}
+	}
+
+	return params
+}
+
+// Builds a key for the given route path where wildcards names are removed since routes
+// only differing by their wildcards names are the same.
+func routeKey(path string) string {
+	segments := strings.Split(path, "/")
+
+	for i, segment := range segments {
+		if isWildcard(segment) {
+			segments[i] = segment[:1]
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func isWildcard(segment string) bool {
+	return strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*")
+}
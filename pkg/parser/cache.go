@@ -0,0 +1,442 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/YuukanOO/ease/pkg/crypto"
+	"github.com/YuukanOO/ease/pkg/diagnostic"
+)
+
+const (
+	cacheFormatVersion = 3 // Bump it whenever cached structures change
+	cacheFilePerm      = 0644
+	cacheDirPerm       = 0755
+	cacheNameLength    = 16
+	codeCache          = "cache"
+)
+
+type (
+	// Cache of parsed declarations, one entry per package. Entries are keyed on the content
+	// of the package files so unchanged packages do not have to be loaded and parsed again.
+	Cache struct {
+		dir string
+		key string
+	}
+
+	cachedPackage struct {
+		Version int
+		Hash    string
+		Path    string
+		Names   map[string]string // Declared names of the package and of its imports by path
+		Decl    cachedDecl
+		Types   []cachedType
+		Funcs   []cachedFunc
+	}
+
+	cachedDecl struct {
+		Name       string                   `json:",omitempty"`
+		Position   token.Position           `json:",omitempty"`
+		Doc        string                   `json:",omitempty"`
+		Directives []cachedDirective        `json:",omitempty"`
+		Errors     []*diagnostic.Diagnostic `json:",omitempty"`
+	}
+
+	cachedDirective struct {
+		Raw      string
+		Position token.Position
+	}
+
+	cachedType struct {
+		Decl       cachedDecl
+		TypeParams []string      `json:",omitempty"`
+		Fields     []cachedField `json:",omitempty"`
+	}
+
+	cachedField struct {
+		Var      cachedVar
+		Tag      string `json:",omitempty"`
+		Embedded bool   `json:",omitempty"`
+	}
+
+	cachedFunc struct {
+		Decl       cachedDecl
+		TypeParams []string    `json:",omitempty"`
+		Recv       *cachedVar  `json:",omitempty"`
+		Params     []cachedVar `json:",omitempty"`
+		Returns    []cachedVar `json:",omitempty"`
+	}
+
+	cachedVar struct {
+		Decl   cachedDecl
+		Source string
+		Expr   *cachedExpr
+	}
+
+	cachedExpr struct {
+		Kind     ExprKind
+		Package  string        `json:",omitempty"` // Package path of a named type, empty for builtins
+		Name     string        `json:",omitempty"` // Name of the named type or type parameter
+		Args     []*cachedExpr `json:",omitempty"`
+		Key      *cachedExpr   `json:",omitempty"`
+		Elem     *cachedExpr   `json:",omitempty"`
+		Len      string        `json:",omitempty"`
+		Dir      ast.ChanDir   `json:",omitempty"`
+		Variadic bool          `json:",omitempty"`
+		Source   string        `json:",omitempty"`
+	}
+)
+
+// NewCache builds a cache stored in the given directory. The key must change whenever
+// the way declarations are parsed may change, such as the Go or ease versions, since it
+// is part of every entry hash.
+func NewCache(dir, key string) *Cache {
+	return &Cache{
+		dir: dir,
+		key: key,
+	}
+}
+
+// Computes the hash of a package from its path, the content of its files and the prefix of
+// directives since it changes the way they are parsed.
+func (c *Cache) hash(prefix, pkgPath string, files []string) (string, error) {
+	h := sha256.New()
+
+	io.WriteString(h, c.key)
+	io.WriteString(h, prefix+":")
+	io.WriteString(h, pkgPath)
+
+	for _, file := range files {
+		f, err := os.Open(file)
+
+		if err != nil {
+			return "", err
+		}
+
+		io.WriteString(h, filepath.Base(file))
+		_, err = io.Copy(h, f)
+		f.Close()
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Retrieve the entry of the given package if it matches the given hash. Unreadable
+// entries are considered missing.
+func (c *Cache) load(pkgPath, hash string) (*cachedPackage, bool) {
+	data, err := os.ReadFile(c.path(pkgPath))
+
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cachedPackage
+
+	if err = json.Unmarshal(data, &entry); err != nil ||
+		entry.Version != cacheFormatVersion || entry.Hash != hash || entry.Path != pkgPath {
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+func (c *Cache) store(entry *cachedPackage) error {
+	data, err := json.Marshal(entry)
+
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(c.dir, cacheDirPerm); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a concurrent run never reads a partial entry
+	tmp, err := os.CreateTemp(c.dir, "entry-*")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Chmod(tmp.Name(), cacheFilePerm)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path(entry.Path))
+}
+
+func (c *Cache) path(pkgPath string) string {
+	return filepath.Join(c.dir, crypto.Prefix(pkgPath, cacheNameLength)+".json")
+}
+
+// Builds the cache entry of the given package from declarations registered in the result.
+func (r *result) encodePackage(pkg *Package, hash string, names map[string]string) *cachedPackage {
+	entry := &cachedPackage{
+		Version: cacheFormatVersion,
+		Hash:    hash,
+		Path:    pkg.Path(),
+		Names:   names,
+		Decl:    encodeDecl(pkg.Decl),
+	}
+
+	for _, typ := range r.types.Items() {
+		// Only keep declared types, referenced ones are restored when needed
+		if typ.pkg != pkg || typ.decl == nil {
+			continue
+		}
+
+		cached := cachedType{
+			Decl:       encodeDecl(typ.Decl),
+			TypeParams: typ.TypeParams(),
+		}
+
+		for _, field := range typ.Fields() {
+			cached.Fields = append(cached.Fields, cachedField{
+				Var:      encodeVar(field.Var),
+				Tag:      field.tag,
+				Embedded: field.embedded,
+			})
+		}
+
+		entry.Types = append(entry.Types, cached)
+	}
+
+	for _, fn := range r.funcs.Items() {
+		if fn.pkg != pkg {
+			continue
+		}
+
+		cached := cachedFunc{
+			Decl:       encodeDecl(fn.Decl),
+			TypeParams: fn.TypeParams(),
+			Params:     encodeVars(fn.Params()),
+			Returns:    encodeVars(fn.Returns()),
+		}
+
+		if recv := fn.Recv(); recv != nil {
+			v := encodeVar(recv)
+			cached.Recv = &v
+		}
+
+		entry.Funcs = append(entry.Funcs, cached)
+	}
+
+	return entry
+}
+
+// Restores declarations of the given cache entry, they must be registered as parsed ones.
+// Funcs params and struct fields are restored lazily, as they are when parsed from the
+// source, so every declared type is known by then.
+func (r *result) restorePackage(entry *cachedPackage) *FileResult {
+	pkg := r.Package(entry.Path)
+	pkg.Decl = restoreDecl(entry.Decl, r.prefix)
+	file := &FileResult{parent: r, pkg: pkg}
+
+	for i := range entry.Types {
+		file.types = append(file.types, &Type{
+			Decl:       restoreDecl(entry.Types[i].Decl, r.prefix),
+			file:       file,
+			pkg:        pkg,
+			cached:     &entry.Types[i],
+			typeParams: entry.Types[i].TypeParams,
+		})
+	}
+
+	for i := range entry.Funcs {
+		file.funcs = append(file.funcs, &Func{
+			Decl:   restoreDecl(entry.Funcs[i].Decl, r.prefix),
+			file:   file,
+			pkg:    pkg,
+			cached: &entry.Funcs[i],
+		})
+	}
+
+	return file
+}
+
+func (f *Func) restore() {
+	f.typeParams = f.cached.TypeParams
+	f.params = f.file.restoreVars(f.cached.Params)
+	f.returns = f.file.restoreVars(f.cached.Returns)
+
+	if f.cached.Recv != nil {
+		f.recv = f.file.restoreVar(*f.cached.Recv)
+	}
+}
+
+func (t *Type) restore() {
+	for _, cached := range t.cached.Fields {
+		t.fields = append(t.fields, &Field{
+			Var:      t.file.restoreVar(cached.Var),
+			tag:      cached.Tag,
+			embedded: cached.Embedded,
+		})
+	}
+}
+
+func encodeDecl(d *Decl) cachedDecl {
+	cached := cachedDecl{
+		Name:     d.Name(),
+		Position: d.Position(),
+		Doc:      d.Doc(),
+		Errors:   d.directivesErrors(),
+	}
+
+	for _, directive := range d.AllDirectives() {
+		cached.Directives = append(cached.Directives, cachedDirective{
+			Raw:      directive.raw,
+			Position: directive.Position,
+		})
+	}
+
+	return cached
+}
+
+// Builds an already parsed declaration from its cached counterpart.
+func restoreDecl(cached cachedDecl, prefix string) *Decl {
+	decl := &Decl{
+		name:     cached.Name,
+		prefix:   prefix,
+		position: cached.Position,
+		doc:      cached.Doc,
+		errors:   cached.Errors,
+	}
+
+	for _, d := range cached.Directives {
+		directive, err := parseDirective(prefix, d.Raw)
+
+		// Should never happen since only valid directives are cached
+		if err != nil || directive == nil {
+			continue
+		}
+
+		directive.Position = d.Position
+		decl.directives = append(decl.directives, directive)
+	}
+
+	decl.lazy.Do(func() {})
+
+	return decl
+}
+
+func encodeVars(vars Vars) []cachedVar {
+	result := make([]cachedVar, len(vars))
+
+	for i, v := range vars {
+		result[i] = encodeVar(v)
+	}
+
+	return result
+}
+
+func encodeVar(v *Var) cachedVar {
+	return cachedVar{
+		Decl:   encodeDecl(v.Decl),
+		Source: v.source,
+		Expr:   encodeExpr(v.expr),
+	}
+}
+
+func (r *FileResult) restoreVars(cached []cachedVar) Vars {
+	vars := make(Vars, len(cached))
+
+	for i, v := range cached {
+		vars[i] = r.restoreVar(v)
+	}
+
+	return vars
+}
+
+func (r *FileResult) restoreVar(cached cachedVar) *Var {
+	return &Var{
+		Decl:   restoreDecl(cached.Decl, r.parent.prefix),
+		expr:   r.restoreExpr(cached.Expr),
+		source: cached.Source,
+	}
+}
+
+func encodeExpr(e *TypeExpr) *cachedExpr {
+	if e == nil {
+		return nil
+	}
+
+	cached := &cachedExpr{
+		Kind:     e.kind,
+		Name:     e.name,
+		Key:      encodeExpr(e.key),
+		Elem:     encodeExpr(e.elem),
+		Len:      e.len,
+		Dir:      e.dir,
+		Variadic: e.variadic,
+		Source:   e.source,
+	}
+
+	if e.typ != nil {
+		cached.Name = e.typ.Name()
+
+		if pkg := e.typ.Package(); pkg != nil {
+			cached.Package = pkg.Path()
+		}
+	}
+
+	for _, arg := range e.args {
+		cached.Args = append(cached.Args, encodeExpr(arg))
+	}
+
+	return cached
+}
+
+func (r *FileResult) restoreExpr(cached *cachedExpr) *TypeExpr {
+	if cached == nil {
+		return nil
+	}
+
+	e := &TypeExpr{
+		kind:     cached.Kind,
+		key:      r.restoreExpr(cached.Key),
+		elem:     r.restoreExpr(cached.Elem),
+		len:      cached.Len,
+		dir:      cached.Dir,
+		variadic: cached.Variadic,
+		source:   cached.Source,
+	}
+
+	if cached.Kind == ExprKindNamed {
+		var pkg *Package
+
+		if cached.Package != "" {
+			pkg = r.parent.Package(cached.Package)
+		}
+
+		e.typ = r.parent.Type(pkg, ast.NewIdent(cached.Name))
+	} else {
+		e.name = cached.Name
+	}
+
+	for _, arg := range cached.Args {
+		e.args = append(e.args, r.restoreExpr(arg))
+	}
+
+	return e
+}
@@ -5,19 +5,29 @@ import (
 	"go/token"
 	"strings"
 	"sync"
+
+	"github.com/YuukanOO/ease/pkg/diagnostic"
 )
 
 // Base type for all declarations.
 type Decl struct {
 	lazy       sync.Once
+	fset       *token.FileSet
+	prefix     string // Prefix of directives in comments
+	pos        token.Pos
+	position   token.Position // Resolved position of declarations restored from the cache
 	comments   []*ast.CommentGroup
 	name       string
 	doc        string
-	directives map[string]*Directive
+	directives []*Directive // Every directive in source order
+	errors     []*diagnostic.Diagnostic
 }
 
-func newDeclaration(ident *ast.Ident, comments ...*ast.CommentGroup) *Decl {
+func newDeclaration(fset *token.FileSet, prefix string, pos token.Pos, ident *ast.Ident, comments ...*ast.CommentGroup) *Decl {
 	decl := &Decl{
+		fset:     fset,
+		prefix:   prefix,
+		pos:      pos,
 		comments: comments,
 	}
 
@@ -31,39 +41,113 @@ func newDeclaration(ident *ast.Ident, comments ...*ast.CommentGroup) *Decl {
 func (d *Decl) IsExported() bool { return token.IsExported(d.name) }
 func (d *Decl) Name() string     { return d.name }
 
+// Position returns the location of the declaration in the source code. It is not valid
+// for declarations which were not parsed from source, such as builtin types.
+func (d *Decl) Position() token.Position {
+	if d.fset == nil || !d.pos.IsValid() {
+		return d.position
+	}
+
+	return d.fset.Position(d.pos)
+}
+
 func (d *Decl) Doc() string {
 	d.parse()
 	return d.doc
 }
 
-// Returns the directive with the given name if it exists.
+// Returns the first directive with the given name if it exists.
 func (d *Decl) Directive(name string) (*Directive, bool) {
 	d.parse()
-	directive, found := d.directives[name]
-	return directive, found
+
+	for _, directive := range d.directives {
+		if directive.Name == name {
+			return directive, true
+		}
+	}
+
+	return nil, false
+}
+
+// Returns every occurrence of the directive with the given name in source order.
+func (d *Decl) Directives(name string) []*Directive {
+	d.parse()
+
+	var result []*Directive
+
+	for _, directive := range d.directives {
+		if directive.Name == name {
+			result = append(result, directive)
+		}
+	}
+
+	return result
+}
+
+// Returns every directive attached to this declaration in source order.
+func (d *Decl) AllDirectives() []*Directive {
+	d.parse()
+	return d.directives
+}
+
+// Returns syntax errors of directives attached to this declaration.
+func (d *Decl) directivesErrors() []*diagnostic.Diagnostic {
+	d.parse()
+	return d.errors
 }
 
 func (d *Decl) parse() {
 	d.lazy.Do(func() {
-		d.directives = make(map[string]*Directive)
-
-		var trimmed string
+		var (
+			trimmed string
+			pending string    // Directive content waiting for its continuation lines
+			start   token.Pos // Position of the first line of the current directive
+		)
 
 		for _, group := range d.comments {
 			if group == nil {
 				continue
 			}
 
-			for _, line := range group.List {
+			for i, line := range group.List {
 				trimmed = strings.Trim(line.Text, "/ ")
-				directive := tryParseDirective(trimmed)
 
-				if directive != nil {
-					d.directives[directive.Name] = directive
+				if pending != "" {
+					trimmed = pending + " " + trimmed
+				} else if isDirective(d.prefix, trimmed) {
+					start = line.Slash
 				} else {
 					d.doc += trimmed + "\n"
+					continue
+				}
+
+				// Multi-line directive, wait for the next line unless the group is finished
+				if strings.HasSuffix(trimmed, directiveContinuationSuffix) && i < len(group.List)-1 {
+					pending = strings.TrimSpace(strings.TrimSuffix(trimmed, directiveContinuationSuffix))
+					continue
 				}
+
+				pending = ""
+				d.addDirective(strings.TrimSuffix(trimmed, directiveContinuationSuffix), start)
 			}
 		}
 	})
 }
+
+func (d *Decl) addDirective(comment string, pos token.Pos) {
+	var position token.Position
+
+	if d.fset != nil {
+		position = d.fset.Position(pos)
+	}
+
+	directive, err := parseDirective(d.prefix, comment)
+
+	if err != nil {
+		d.errors = append(d.errors, diagnostic.Errorf(position, codeDirectiveSyntax, "%v", err))
+		return
+	}
+
+	directive.Position = position
+	d.directives = append(d.directives, directive)
+}
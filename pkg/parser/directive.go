@@ -1,50 +1,323 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"go/token"
 	"regexp"
+	"strconv"
+	"strings"
 )
 
-const directivePrefix = "ease"
+const (
+	DefaultDirectivePrefix      = "ease" // Prefix of directives, such as ease:api
+	directiveContinuationSuffix = `\`
+	codeDirectiveSyntax         = "directive-syntax"
+)
 
 var (
-	reKeyValueExtractor = regexp.MustCompile(`([\w]*?)=([^ ]*)`)
-	reDirectiveName     = regexp.MustCompile(fmt.Sprintf(`^%s:(\w+)`, directivePrefix))
+	ErrDirectiveSyntax = errors.New("invalid directive syntax")
+
+	reDirectiveName = regexp.MustCompile(`^\w+`)
 )
 
+// Represents a single directive parsed from a comment such as:
+//
+//	ease:api method=POST path=/api/todos summary="Create a todo" use=auth,audit deprecated
+//
+// Params are written as key=value where the value is either a bare word, a quoted string
+// or a comma separated list of those (optionally wrapped in brackets). Values without a
+// key are flags / positional arguments. A directive can span multiple lines by ending
+// them with a backslash.
 type Directive struct {
-	Name   string            // Name of the directive
-	Params map[string]string // Key values of parsed directive params
+	Name     string         // Name of the directive
+	Args     []string       // Positional arguments (values without a key), also used as flags
+	Position token.Position // Position of the comment containing the directive
+
+	raw    string              // Comment the directive was parsed from
+	keys   []string            // Param keys in the order they appear
+	params map[string][]string // Values of each param, repeated keys accumulate values
 }
 
-// Try to parse a directive from a sanitized comment (without the //).
-func tryParseDirective(comment string) *Directive {
-	matches := reDirectiveName.FindStringSubmatch(comment)
+// Checks if the given param or flag is present on the directive.
+func (d *Directive) Has(key string) bool {
+	if _, found := d.params[key]; found {
+		return true
+	}
 
-	if len(matches) < 2 {
-		return nil
+	for _, arg := range d.Args {
+		if arg == key {
+			return true
+		}
 	}
 
-	return &Directive{
-		Name:   matches[1],
-		Params: parseDirectiveParams(comment),
+	return false
+}
+
+// Value returns the last value of the given param or an empty string if not present.
+func (d *Directive) Value(key string) string {
+	values := d.params[key]
+
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[len(values)-1]
+}
+
+// List returns every values of the given param, including the ones of repeated keys.
+func (d *Directive) List(key string) []string { return d.params[key] }
+
+// Keys returns param keys in the order they first appear in the directive.
+func (d *Directive) Keys() []string { return d.keys }
+
+// Bool returns true if the given flag is present or if the param is set to a truthy value.
+func (d *Directive) Bool(key string) (bool, error) {
+	if _, found := d.params[key]; !found {
+		return d.Has(key), nil
+	}
+
+	value, err := strconv.ParseBool(d.Value(key))
+
+	if err != nil {
+		return false, fmt.Errorf("%w: %s expects a boolean, got %s", ErrDirectiveSyntax, key, d.Value(key))
+	}
+
+	return value, nil
+}
+
+// Int returns the given param as an integer, 0 if not present.
+func (d *Directive) Int(key string) (int, error) {
+	if _, found := d.params[key]; !found {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(d.Value(key))
+
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s expects an integer, got %s", ErrDirectiveSyntax, key, d.Value(key))
+	}
+
+	return value, nil
+}
+
+// Checks if the sanitized comment (without the //) looks like a directive.
+func isDirective(prefix, comment string) bool {
+	_, _, ok := directiveName(prefix, comment)
+	return ok
+}
+
+// Extracts the name of the directive in the given comment and where it ends.
+func directiveName(prefix, comment string) (string, int, bool) {
+	if !strings.HasPrefix(comment, prefix+":") {
+		return "", 0, false
 	}
+
+	start := len(prefix) + 1
+	name := reDirectiveName.FindString(comment[start:])
+
+	return name, start + len(name), name != ""
+}
+
+// ParseDirective parses a directive from a sanitized comment (without the //). Returns nil without
+// any error if the comment is not a directive.
+func ParseDirective(comment string) (*Directive, error) {
+	return parseDirective(DefaultDirectivePrefix, comment)
 }
 
-// Parse raw directive params into a map of key / values.
-func parseDirectiveParams(params string) map[string]string {
-	// FIXME: This is sufficient for now, but we might want to use a real parser in the future
-	kv := reKeyValueExtractor.FindAllStringSubmatch(params, -1)
-	result := make(map[string]string, len(kv))
+// Same as ParseDirective for directives starting with the given prefix.
+func parseDirective(prefix, comment string) (*Directive, error) {
+	name, end, ok := directiveName(prefix, comment)
+
+	if !ok {
+		return nil, nil
+	}
+
+	directive := &Directive{
+		Name:   name,
+		raw:    comment,
+		params: make(map[string][]string),
+	}
+
+	s := &directiveScanner{src: comment, pos: end}
+
+	if !s.done() && !s.isSpace() {
+		return nil, s.errorf("unexpected character %q after directive name", s.peek())
+	}
+
+	for {
+		s.skipSpaces()
+
+		if s.done() {
+			return directive, nil
+		}
 
-	// Convert the regex result into a map
-	for _, match := range kv {
-		if len(match) != 3 {
+		quoted := s.peek() == '"'
+		value, err := s.element()
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Not a key, that's a positional argument which may be a list too
+		if quoted || s.done() || s.peek() != '=' {
+			values, err := s.list(value)
+
+			if err != nil {
+				return nil, err
+			}
+
+			directive.Args = append(directive.Args, values...)
 			continue
 		}
 
-		result[match[1]] = match[2]
+		s.pos++ // Skip the =
+
+		if value == "" {
+			return nil, s.errorf("missing param name")
+		}
+
+		values, err := s.values()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, found := directive.params[value]; !found {
+			directive.keys = append(directive.keys, value)
+		}
+
+		directive.params[value] = append(directive.params[value], values...)
 	}
+}
+
+// Simple scanner used to tokenize directive params.
+type directiveScanner struct {
+	src string
+	pos int
+}
+
+func (s *directiveScanner) done() bool    { return s.pos >= len(s.src) }
+func (s *directiveScanner) peek() byte    { return s.src[s.pos] }
+func (s *directiveScanner) isSpace() bool { return s.peek() == ' ' || s.peek() == '\t' }
+
+func (s *directiveScanner) skipSpaces() {
+	for !s.done() && s.isSpace() {
+		s.pos++
+	}
+}
+
+// Parses the value of a param: a single element, a comma separated list or a bracketed list.
+func (s *directiveScanner) values() ([]string, error) {
+	if s.done() || s.isSpace() {
+		return nil, s.errorf("missing value")
+	}
+
+	if s.peek() != '[' {
+		first, err := s.element()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return s.list(first)
+	}
+
+	s.pos++ // Skip the [
+	s.skipSpaces()
+
+	var values []string
+
+	for !s.done() && s.peek() != ']' {
+		value, err := s.element()
+
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+		s.skipSpaces()
+
+		if !s.done() && s.peek() == ',' {
+			s.pos++
+			s.skipSpaces()
+		}
+	}
+
+	if s.done() {
+		return nil, s.errorf("unterminated list")
+	}
+
+	s.pos++ // Skip the ]
+
+	return values, nil
+}
+
+// Reads the remaining elements of a comma separated list starting with the given one.
+func (s *directiveScanner) list(first string) ([]string, error) {
+	values := []string{first}
+
+	for !s.done() && s.peek() == ',' {
+		s.pos++
+		s.skipSpaces()
+
+		value, err := s.element()
+
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// Reads a single element, being a quoted string or a bare word.
+func (s *directiveScanner) element() (string, error) {
+	if s.done() {
+		return "", s.errorf("missing value")
+	}
+
+	start := s.pos
+
+	if s.peek() == '"' {
+		s.pos++
+
+		for !s.done() && s.peek() != '"' {
+			if s.peek() == '\\' {
+				s.pos++
+			}
+
+			s.pos++
+		}
+
+		if s.done() {
+			return "", s.errorf("unterminated string")
+		}
+
+		s.pos++
+
+		value, err := strconv.Unquote(s.src[start:s.pos])
+
+		if err != nil {
+			return "", s.errorf("invalid string %s", s.src[start:s.pos])
+		}
+
+		return value, nil
+	}
+
+	for !s.done() && !s.isSpace() && !strings.ContainsRune(`=,"[]`, rune(s.peek())) {
+		s.pos++
+	}
+
+	if start == s.pos && !s.done() && s.peek() != '=' {
+		return "", s.errorf("unexpected character %q", s.peek())
+	}
+
+	return s.src[start:s.pos], nil
+}
 
-	return result
+func (s *directiveScanner) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at offset %d in %s", ErrDirectiveSyntax, fmt.Sprintf(format, args...), s.pos, s.src)
 }
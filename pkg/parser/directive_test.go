@@ -0,0 +1,75 @@
+package parser_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/YuukanOO/ease/pkg/parser"
+)
+
+func TestParseDirective(t *testing.T) {
+	t.Run("should returns nil if the comment is not a directive", func(t *testing.T) {
+		directive, err := parser.ParseDirective("Some documentation")
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if directive != nil {
+			t.Errorf("expected no directive, got %v", directive)
+		}
+	})
+
+	t.Run("should parse params, quoted strings, lists and flags", func(t *testing.T) {
+		directive, err := parser.ParseDirective(`ease:api method=POST summary="Create a \"todo\"" use=auth,audit tag=[a, b] tag=c deprecated`)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if directive.Name != "api" {
+			t.Errorf("expected name to be api, got %s", directive.Name)
+		}
+
+		if directive.Value("method") != "POST" {
+			t.Errorf("expected method to be POST, got %s", directive.Value("method"))
+		}
+
+		if directive.Value("summary") != `Create a "todo"` {
+			t.Errorf("expected summary to be unquoted, got %s", directive.Value("summary"))
+		}
+
+		if use := directive.List("use"); !reflect.DeepEqual(use, []string{"auth", "audit"}) {
+			t.Errorf("expected use to be a list, got %v", use)
+		}
+
+		if tags := directive.List("tag"); !reflect.DeepEqual(tags, []string{"a", "b", "c"}) {
+			t.Errorf("expected repeated tag to accumulate values, got %v", tags)
+		}
+
+		if deprecated, err := directive.Bool("deprecated"); err != nil || !deprecated {
+			t.Errorf("expected deprecated flag to be set, got %v, %v", deprecated, err)
+		}
+
+		if keys := directive.Keys(); !reflect.DeepEqual(keys, []string{"method", "summary", "use", "tag"}) {
+			t.Errorf("expected keys in order, got %v", keys)
+		}
+	})
+
+	t.Run("should report syntax errors", func(t *testing.T) {
+		for _, comment := range []string{
+			`ease:api summary="unterminated`,
+			`ease:api path=`,
+			`ease:api =value`,
+			`ease:api tag=[a, b`,
+			`ease:api-nope`,
+		} {
+			_, err := parser.ParseDirective(comment)
+
+			if !errors.Is(err, parser.ErrDirectiveSyntax) {
+				t.Errorf("expected a syntax error for %s, got %v", comment, err)
+			}
+		}
+	})
+}
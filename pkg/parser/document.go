@@ -0,0 +1,365 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"sort"
+)
+
+// Version of the document format, bumped whenever a field is removed or its meaning changes.
+// Adding fields is not considered a breaking change.
+const DocumentVersion = 1
+
+var exprKindNames = map[ExprKind]string{
+	ExprKindUnknown:   "unknown",
+	ExprKindNamed:     "named",
+	ExprKindPointer:   "pointer",
+	ExprKindSlice:     "slice",
+	ExprKindArray:     "array",
+	ExprKindMap:       "map",
+	ExprKindChan:      "chan",
+	ExprKindFunc:      "func",
+	ExprKindStruct:    "struct",
+	ExprKindInterface: "interface",
+	ExprKindTypeParam: "typeparam",
+}
+
+type (
+	// Inspector is implemented by extensions which want to expose what they extracted in
+	// the inspected document.
+	Inspector interface {
+		// Returns the key of the extension in the document and a JSON serializable value.
+		Inspect() (string, any)
+	}
+
+	// Stable and serializable representation of a parse result, consumed by tools which
+	// are not written in Go.
+	Document struct {
+		Version     int              `json:"version"`
+		Packages    []*DocPackage    `json:"packages"`
+		Types       []*DocType       `json:"types"`
+		Funcs       []*DocFunc       `json:"funcs"`
+		Diagnostics []*DocDiagnostic `json:"diagnostics"`
+		Extensions  map[string]any   `json:"extensions,omitempty"`
+	}
+
+	DocPosition struct {
+		File   string `json:"file"`
+		Line   int    `json:"line"`
+		Column int    `json:"column"`
+	}
+
+	DocDirective struct {
+		Name     string              `json:"name"`
+		Args     []string            `json:"args,omitempty"`
+		Params   map[string][]string `json:"params,omitempty"`
+		Position *DocPosition        `json:"position,omitempty"`
+	}
+
+	DocDecl struct {
+		Name       string          `json:"name,omitempty"`
+		Doc        string          `json:"doc,omitempty"`
+		Position   *DocPosition    `json:"position,omitempty"`
+		Directives []*DocDirective `json:"directives,omitempty"`
+	}
+
+	DocPackage struct {
+		DocDecl
+		Path string `json:"path"`
+	}
+
+	DocType struct {
+		DocDecl
+		ID         string   `json:"id"` // Fully qualified name
+		Package    string   `json:"package"`
+		TypeParams []string `json:"typeParams,omitempty"`
+	}
+
+	DocFunc struct {
+		DocDecl
+		ID         string    `json:"id"` // Unique identifier, see Func.ID
+		Package    string    `json:"package"`
+		TypeParams []string  `json:"typeParams,omitempty"`
+		Recv       *DocVar   `json:"recv,omitempty"`
+		Params     []*DocVar `json:"params"`
+		Returns    []*DocVar `json:"returns"`
+	}
+
+	DocVar struct {
+		DocDecl
+		Type *DocExpr `json:"type"`
+	}
+
+	DocExpr struct {
+		Kind     string     `json:"kind"`
+		String   string     `json:"string"`            // Fully qualified representation
+		Type     string     `json:"type,omitempty"`    // Fully qualified name of a named type
+		Package  string     `json:"package,omitempty"` // Package of a named type, empty for builtins
+		Name     string     `json:"name,omitempty"`    // Name of a named type or type parameter
+		Args     []*DocExpr `json:"args,omitempty"`
+		Key      *DocExpr   `json:"key,omitempty"`
+		Elem     *DocExpr   `json:"elem,omitempty"`
+		Len      string     `json:"len,omitempty"`
+		Dir      string     `json:"dir,omitempty"` // send, recv or both for channels
+		Variadic bool       `json:"variadic,omitempty"`
+	}
+
+	DocDiagnostic struct {
+		Severity string       `json:"severity"`
+		Code     string       `json:"code"`
+		Message  string       `json:"message"`
+		Position *DocPosition `json:"position,omitempty"`
+	}
+)
+
+func (k ExprKind) String() string { return exprKindNames[k] }
+
+// Inspect builds the document of the given result. Extensions implementing Inspector
+// add their own part under their key.
+func Inspect(result Result, extensions ...Extension) *Document {
+	doc := &Document{
+		Version:     DocumentVersion,
+		Packages:    make([]*DocPackage, 0),
+		Types:       make([]*DocType, 0),
+		Funcs:       make([]*DocFunc, 0),
+		Diagnostics: make([]*DocDiagnostic, 0),
+	}
+
+	for _, pkg := range result.Packages() {
+		// Imported packages are only referenced by their path in expressions
+		if !pkg.IsDeclared() {
+			continue
+		}
+
+		doc.Packages = append(doc.Packages, &DocPackage{
+			DocDecl: describeDecl(pkg.Decl),
+			Path:    pkg.Path(),
+		})
+	}
+
+	for _, typ := range result.Types() {
+		// Builtin and referenced types are described by expressions using them
+		if !typ.IsDeclared() {
+			continue
+		}
+
+		doc.Types = append(doc.Types, &DocType{
+			DocDecl:    describeDecl(typ.Decl),
+			ID:         typ.String(),
+			Package:    typ.Package().Path(),
+			TypeParams: typ.TypeParams(),
+		})
+	}
+
+	for _, fn := range result.Funcs() {
+		doc.Funcs = append(doc.Funcs, DescribeFunc(fn))
+	}
+
+	for _, d := range result.Diagnostics().Items() {
+		doc.Diagnostics = append(doc.Diagnostics, &DocDiagnostic{
+			Severity: d.Severity.String(),
+			Code:     d.Code,
+			Message:  d.Message,
+			Position: describePosition(d.Position),
+		})
+	}
+
+	for _, extension := range extensions {
+		inspector, ok := extension.(Inspector)
+
+		if !ok {
+			continue
+		}
+
+		if doc.Extensions == nil {
+			doc.Extensions = make(map[string]any)
+		}
+
+		key, value := inspector.Inspect()
+		doc.Extensions[key] = value
+	}
+
+	sort.SliceStable(doc.Packages, func(i, j int) bool { return doc.Packages[i].Path < doc.Packages[j].Path })
+	sort.SliceStable(doc.Types, func(i, j int) bool { return doc.Types[i].ID < doc.Types[j].ID })
+	sort.SliceStable(doc.Funcs, func(i, j int) bool { return doc.Funcs[i].ID < doc.Funcs[j].ID })
+
+	return doc
+}
+
+// Describes the given func as it appears in a document.
+func DescribeFunc(fn *Func) *DocFunc {
+	doc := &DocFunc{
+		DocDecl:    describeDecl(fn.Decl),
+		ID:         fn.ID(),
+		TypeParams: fn.TypeParams(),
+		Params:     describeVars(fn.Params()),
+		Returns:    describeVars(fn.Returns()),
+	}
+
+	if pkg := fn.Package(); pkg != nil {
+		doc.Package = pkg.Path()
+	}
+
+	if recv := fn.Recv(); recv != nil {
+		doc.Recv = DescribeVar(recv)
+	}
+
+	return doc
+}
+
+// Describes the given var as it appears in a document.
+func DescribeVar(v *Var) *DocVar {
+	return &DocVar{
+		DocDecl: describeDecl(v.Decl),
+		Type:    DescribeExpr(v.TypeExpr()),
+	}
+}
+
+// Describes the given type expression as it appears in a document.
+func DescribeExpr(e *TypeExpr) *DocExpr {
+	if e == nil {
+		return nil
+	}
+
+	doc := &DocExpr{
+		Kind:     e.Kind().String(),
+		String:   e.String(),
+		Key:      DescribeExpr(e.Key()),
+		Elem:     DescribeExpr(e.Elem()),
+		Len:      e.Len(),
+		Variadic: e.IsVariadic(),
+		Name:     e.Name(),
+	}
+
+	if typ := e.Type(); typ != nil {
+		doc.Type = typ.String()
+		doc.Name = typ.Name()
+
+		if pkg := typ.Package(); pkg != nil {
+			doc.Package = pkg.Path()
+		}
+	}
+
+	if e.Kind() == ExprKindChan {
+		switch e.ChanDir() {
+		case ast.SEND:
+			doc.Dir = "send"
+		case ast.RECV:
+			doc.Dir = "recv"
+		default:
+			doc.Dir = "both"
+		}
+	}
+
+	for _, arg := range e.Args() {
+		doc.Args = append(doc.Args, DescribeExpr(arg))
+	}
+
+	return doc
+}
+
+func describeVars(vars Vars) []*DocVar {
+	result := make([]*DocVar, len(vars))
+
+	for i, v := range vars {
+		result[i] = DescribeVar(v)
+	}
+
+	return result
+}
+
+func describeDecl(d *Decl) DocDecl {
+	doc := DocDecl{
+		Name:     d.Name(),
+		Doc:      d.Doc(),
+		Position: describePosition(d.Position()),
+	}
+
+	for _, directive := range d.AllDirectives() {
+		described := &DocDirective{
+			Name:     directive.Name,
+			Args:     directive.Args,
+			Position: describePosition(directive.Position),
+		}
+
+		for _, key := range directive.Keys() {
+			if described.Params == nil {
+				described.Params = make(map[string][]string)
+			}
+
+			described.Params[key] = directive.List(key)
+		}
+
+		doc.Directives = append(doc.Directives, described)
+	}
+
+	return doc
+}
+
+// RelativeTo makes paths of files inside one of the given directories relative to the
+// first one containing them, with forward slashes, so the document does not depend on
+// where the module is. Other files are left as is.
+func (d *Document) RelativeTo(dirs ...string) *Document {
+	var decls []*DocDecl
+
+	for _, pkg := range d.Packages {
+		decls = append(decls, &pkg.DocDecl)
+	}
+
+	for _, typ := range d.Types {
+		decls = append(decls, &typ.DocDecl)
+	}
+
+	for _, fn := range d.Funcs {
+		decls = append(decls, &fn.DocDecl)
+
+		if fn.Recv != nil {
+			decls = append(decls, &fn.Recv.DocDecl)
+		}
+
+		for _, v := range append(fn.Params, fn.Returns...) {
+			decls = append(decls, &v.DocDecl)
+		}
+	}
+
+	for _, decl := range decls {
+		decl.Position.relativeTo(dirs)
+
+		for _, directive := range decl.Directives {
+			directive.Position.relativeTo(dirs)
+		}
+	}
+
+	for _, diagnostic := range d.Diagnostics {
+		diagnostic.Position.relativeTo(dirs)
+	}
+
+	return d
+}
+
+func (p *DocPosition) relativeTo(dirs []string) {
+	if p == nil {
+		return
+	}
+
+	for _, dir := range dirs {
+		if rel, err := filepath.Rel(dir, p.File); dir != "" && err == nil && filepath.IsLocal(rel) {
+			p.File = filepath.ToSlash(rel)
+			return
+		}
+	}
+}
+
+// Describes the given position, nil if it is not valid.
+func describePosition(pos token.Position) *DocPosition {
+	if !pos.IsValid() {
+		return nil
+	}
+
+	return &DocPosition{
+		File:   pos.Filename,
+		Line:   pos.Line,
+		Column: pos.Column,
+	}
+}
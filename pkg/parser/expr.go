@@ -0,0 +1,438 @@
+package parser
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/YuukanOO/ease/pkg/flag"
+)
+
+type ExprKind uint8
+
+const (
+	ExprKindUnknown   ExprKind = iota
+	ExprKindNamed              // Reference to a named type, builtin or declared
+	ExprKindPointer            // *Elem
+	ExprKindSlice              // []Elem, also used for variadic params
+	ExprKindArray              // [Len]Elem
+	ExprKindMap                // map[Key]Elem
+	ExprKindChan               // chan Elem in any direction
+	ExprKindFunc               // Function type, kept as written in the source
+	ExprKindStruct             // Anonymous struct, kept as written in the source
+	ExprKindInterface          // Anonymous interface, kept as written in the source
+	ExprKindTypeParam          // Type parameter of a generic func or type, such as T
+)
+
+// TypeExpr is a recursive representation of a type expression such as map[string][]*Todo
+// so generators can render the exact type of a variable.
+type TypeExpr struct {
+	kind     ExprKind
+	typ      *Type       // Named type for ExprKindNamed
+	args     []*TypeExpr // Type arguments of an instantiated generic type, Todo for Page[Todo]
+	name     string      // Name of the type parameter for ExprKindTypeParam
+	elem     *TypeExpr   // Element of pointers, slices, arrays, maps and channels
+	key      *TypeExpr   // Key of maps
+	len      string      // Length of arrays as written in the source
+	dir      ast.ChanDir
+	variadic bool   // Slice declared as ...Elem
+	source   string // Source of func, struct and interface types
+}
+
+func (e *TypeExpr) Kind() ExprKind       { return e.kind }
+func (e *TypeExpr) Type() *Type          { return e.typ }
+func (e *TypeExpr) Elem() *TypeExpr      { return e.elem }
+func (e *TypeExpr) Key() *TypeExpr       { return e.key }
+func (e *TypeExpr) Len() string          { return e.len }
+func (e *TypeExpr) ChanDir() ast.ChanDir { return e.dir }
+func (e *TypeExpr) IsVariadic() bool     { return e.variadic }
+func (e *TypeExpr) Args() []*TypeExpr    { return e.args }
+func (e *TypeExpr) Name() string         { return e.name }
+
+// Returns the element of a pointer or the expression itself.
+func (e *TypeExpr) Deref() *TypeExpr {
+	if e.kind == ExprKindPointer {
+		return e.elem
+	}
+
+	return e
+}
+
+// Returns the fully qualified expression without its leading pointers. It identifies
+// an instance of a type such as Repo[Todo] no matter how it is referenced.
+func (e *TypeExpr) Instance() string { return e.deref().String() }
+
+// Returns the expression without its leading pointers.
+func (e *TypeExpr) deref() *TypeExpr {
+	cur := e
+
+	for cur.kind == ExprKindPointer {
+		cur = cur.elem
+	}
+
+	return cur
+}
+
+// Checks if the expression references a type parameter, meaning it must be instantiated
+// before being used.
+func (e *TypeExpr) IsGeneric() bool {
+	if e == nil {
+		return false
+	}
+
+	if e.kind == ExprKindTypeParam {
+		return true
+	}
+
+	for _, arg := range e.args {
+		if arg.IsGeneric() {
+			return true
+		}
+	}
+
+	return e.key.IsGeneric() || e.elem.IsGeneric()
+}
+
+// Returns every package referenced by named types of the expression, type arguments included.
+func (e *TypeExpr) Packages() []*Package {
+	var pkgs []*Package
+
+	e.walk(func(t *TypeExpr) {
+		if t.kind == ExprKindNamed && t.typ.Package() != nil {
+			pkgs = append(pkgs, t.typ.Package())
+		}
+	})
+
+	return pkgs
+}
+
+func (e *TypeExpr) walk(fn func(*TypeExpr)) {
+	if e == nil {
+		return
+	}
+
+	fn(e)
+
+	for _, arg := range e.args {
+		arg.walk(fn)
+	}
+
+	e.key.walk(fn)
+	e.elem.walk(fn)
+}
+
+// Returns the number of pointers directly wrapping the inner expression, 2 for **T.
+func (e *TypeExpr) PointerDepth() int {
+	depth := 0
+
+	for cur := e; cur != nil && cur.kind == ExprKindPointer; cur = cur.elem {
+		depth++
+	}
+
+	return depth
+}
+
+// Returns the innermost named type, following elements of composite types and values
+// of maps. Returns nil if the expression ends with an unnamed type.
+func (e *TypeExpr) Underlying() *Type {
+	cur := e
+
+	for cur != nil && cur.kind != ExprKindNamed {
+		cur = cur.elem
+	}
+
+	if cur == nil {
+		return nil
+	}
+
+	return cur.typ
+}
+
+// Render the expression by calling qualifier to reference named types. Func, struct and
+// interface types are rendered as written in the source.
+func (e *TypeExpr) Render(qualifier func(*Type) string) string {
+	var b strings.Builder
+	e.render(&b, qualifier)
+	return b.String()
+}
+
+// Returns the expression with fully qualified named types.
+func (e *TypeExpr) String() string {
+	return e.Render(func(t *Type) string { return t.String() })
+}
+
+func (e *TypeExpr) render(b *strings.Builder, qualifier func(*Type) string) {
+	switch e.kind {
+	case ExprKindNamed:
+		b.WriteString(qualifier(e.typ))
+
+		if len(e.args) > 0 {
+			b.WriteString("[")
+
+			for i, arg := range e.args {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+
+				arg.render(b, qualifier)
+			}
+
+			b.WriteString("]")
+		}
+
+		return
+	case ExprKindTypeParam:
+		b.WriteString(e.name)
+		return
+	case ExprKindPointer:
+		b.WriteString("*")
+	case ExprKindSlice:
+		if e.variadic {
+			b.WriteString("...")
+		} else {
+			b.WriteString("[]")
+		}
+	case ExprKindArray:
+		b.WriteString("[" + e.len + "]")
+	case ExprKindMap:
+		b.WriteString("map[")
+		e.key.render(b, qualifier)
+		b.WriteString("]")
+	case ExprKindChan:
+		switch e.dir {
+		case ast.SEND:
+			b.WriteString("chan<- ")
+		case ast.RECV:
+			b.WriteString("<-chan ")
+		default:
+			b.WriteString("chan ")
+		}
+	default:
+		b.WriteString(e.source)
+		return
+	}
+
+	e.elem.render(b, qualifier)
+}
+
+// Computes flags describing the expression for the Var API, mostly the outermost composite
+// kinds followed until the underlying type is found.
+func (e *TypeExpr) varKind() VarKind {
+	kind := VarKindUnknown
+
+	for cur := e; cur != nil; cur = cur.elem {
+		switch cur.kind {
+		case ExprKindNamed:
+			if cur.typ.IsBuiltin() {
+				return kind | VarKindBuiltin
+			}
+
+			return kind | VarKindIdent
+		case ExprKindPointer:
+			if flag.IsSet(kind, VarKindSlice) {
+				kind |= VarKindSliceOfPointer
+			} else {
+				kind |= VarKindPointer
+			}
+		case ExprKindSlice:
+			kind |= VarKindSlice
+
+			if cur.variadic {
+				kind |= VarKindVariadic
+			}
+		case ExprKindArray:
+			kind |= VarKindArray
+		case ExprKindMap:
+			kind |= VarKindMap
+		case ExprKindChan:
+			kind |= VarKindChan
+		case ExprKindFunc:
+			return kind | VarKindFunc
+		case ExprKindStruct:
+			return kind | VarKindStruct
+		case ExprKindInterface:
+			return kind | VarKindInterface
+		case ExprKindTypeParam:
+			return kind | VarKindTypeParam
+		default:
+			return VarKindUnknown
+		}
+	}
+
+	return kind
+}
+
+// Checks if the expression, which may reference type parameters, matches the target
+// one. Type parameters found along the way are bound to the matching target expression.
+func (e *TypeExpr) unify(target *TypeExpr, bindings map[string]*TypeExpr) bool {
+	if e.kind == ExprKindTypeParam {
+		if bound, found := bindings[e.name]; found {
+			return bound.String() == target.String()
+		}
+
+		bindings[e.name] = target
+		return true
+	}
+
+	if e.kind != target.kind || len(e.args) != len(target.args) {
+		return false
+	}
+
+	switch e.kind {
+	case ExprKindNamed:
+		if e.typ != target.typ {
+			return false
+		}
+
+		for i, arg := range e.args {
+			if !arg.unify(target.args[i], bindings) {
+				return false
+			}
+		}
+
+		return true
+	case ExprKindArray:
+		if e.len != target.len {
+			return false
+		}
+	case ExprKindChan:
+		if e.dir != target.dir {
+			return false
+		}
+	case ExprKindMap:
+		if !e.key.unify(target.key, bindings) {
+			return false
+		}
+	case ExprKindPointer, ExprKindSlice:
+	default:
+		return e.source == target.source
+	}
+
+	return e.elem.unify(target.elem, bindings)
+}
+
+// Returns a copy of the expression where bound type parameters are replaced.
+func (e *TypeExpr) substitute(bindings map[string]*TypeExpr) *TypeExpr {
+	if e == nil || len(bindings) == 0 {
+		return e
+	}
+
+	if e.kind == ExprKindTypeParam {
+		if bound, found := bindings[e.name]; found {
+			return bound
+		}
+
+		return e
+	}
+
+	substituted := *e
+	substituted.key = e.key.substitute(bindings)
+	substituted.elem = e.elem.substitute(bindings)
+
+	if len(e.args) > 0 {
+		substituted.args = make([]*TypeExpr, len(e.args))
+
+		for i, arg := range e.args {
+			substituted.args[i] = arg.substitute(bindings)
+		}
+	}
+
+	return &substituted
+}
+
+// Builds the type expression, the pkg is the one used to resolve unqualified identifiers
+// and typeParams contains names of type parameters in scope.
+func (r *FileResult) parseExpr(expr ast.Expr, pkg *Package, typeParams typeParams) *TypeExpr {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if typeParams.has(t.Name) {
+			return &TypeExpr{kind: ExprKindTypeParam, name: t.Name}
+		}
+
+		return &TypeExpr{kind: ExprKindNamed, typ: r.parent.Type(pkg, t)}
+	case *ast.IndexExpr:
+		return r.parseInstance(t.X, []ast.Expr{t.Index}, pkg, typeParams)
+	case *ast.IndexListExpr:
+		return r.parseInstance(t.X, t.Indices, pkg, typeParams)
+	case *ast.StarExpr:
+		return &TypeExpr{kind: ExprKindPointer, elem: r.parseExpr(t.X, pkg, typeParams)}
+	case *ast.Ellipsis:
+		return &TypeExpr{kind: ExprKindSlice, variadic: true, elem: r.parseExpr(t.Elt, pkg, typeParams)}
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return &TypeExpr{kind: ExprKindArray, len: types.ExprString(t.Len), elem: r.parseExpr(t.Elt, pkg, typeParams)}
+		}
+
+		return &TypeExpr{kind: ExprKindSlice, elem: r.parseExpr(t.Elt, pkg, typeParams)}
+	case *ast.MapType:
+		return &TypeExpr{
+			kind: ExprKindMap,
+			key:  r.parseExpr(t.Key, pkg, typeParams),
+			elem: r.parseExpr(t.Value, pkg, typeParams),
+		}
+	case *ast.ChanType:
+		return &TypeExpr{kind: ExprKindChan, dir: t.Dir, elem: r.parseExpr(t.Value, pkg, typeParams)}
+	case *ast.ParenExpr:
+		return r.parseExpr(t.X, pkg, typeParams)
+	case *ast.FuncType:
+		return &TypeExpr{kind: ExprKindFunc, source: types.ExprString(t)}
+	case *ast.StructType:
+		return &TypeExpr{kind: ExprKindStruct, source: types.ExprString(t)}
+	case *ast.InterfaceType:
+		return &TypeExpr{kind: ExprKindInterface, source: types.ExprString(t)}
+	case *ast.SelectorExpr:
+		// Qualified identifiers can not reference a type parameter
+		if x, ok := t.X.(*ast.Ident); ok {
+			return &TypeExpr{kind: ExprKindNamed, typ: r.parent.Type(r.imports[x.Name], t.Sel)}
+		}
+	}
+
+	return &TypeExpr{kind: ExprKindUnknown, source: types.ExprString(expr)}
+}
+
+// Builds an instantiated generic type such as Page[Todo].
+func (r *FileResult) parseInstance(expr ast.Expr, args []ast.Expr, pkg *Package, typeParams typeParams) *TypeExpr {
+	generic := r.parseExpr(expr, pkg, typeParams)
+
+	if generic.kind != ExprKindNamed {
+		return &TypeExpr{kind: ExprKindUnknown, source: types.ExprString(expr)}
+	}
+
+	generic.args = make([]*TypeExpr, len(args))
+
+	for i, arg := range args {
+		generic.args[i] = r.parseExpr(arg, pkg, typeParams)
+	}
+
+	return generic
+}
+
+// Names of type parameters in scope, in declaration order.
+type typeParams []string
+
+// Builds the type parameters scope from a type parameters list.
+func newTypeParams(fields *ast.FieldList) typeParams {
+	var scope typeParams
+
+	if fields == nil {
+		return scope
+	}
+
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			scope = append(scope, name.Name)
+		}
+	}
+
+	return scope
+}
+
+func (t typeParams) has(name string) bool {
+	for _, param := range t {
+		if param == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,9 +1,15 @@
 package parser
 
 import (
-	"fmt"
 	"go/ast"
 	"sync"
+
+	"github.com/YuukanOO/ease/pkg/diagnostic"
+)
+
+const (
+	codeUnresolvedDependency  = "unresolved-dependency"
+	codeUnsupportedDependency = "unsupported-dependency"
 )
 
 type (
@@ -12,19 +18,21 @@ type (
 
 	Func struct {
 		*Decl
-		lazy    sync.Once
-		file    *FileResult
-		decl    *ast.FuncDecl
-		pkg     *Package
-		recv    *Var
-		params  Vars
-		returns Vars
+		lazy       sync.Once
+		file       *FileResult
+		decl       *ast.FuncDecl
+		pkg        *Package
+		cached     *cachedFunc // Set when restored from the cache instead of an ast declaration
+		typeParams typeParams  // Type parameters of the func or of its generic receiver
+		recv       *Var
+		params     Vars
+		returns    Vars
 	}
 )
 
 func newFunc(at *FileResult, decl *ast.FuncDecl) *Func {
 	return &Func{
-		Decl: newDeclaration(decl.Name, decl.Doc),
+		Decl: newDeclaration(at.parent.fset, at.parent.prefix, decl.Pos(), decl.Name, decl.Doc),
 		file: at,
 		pkg:  at.pkg,
 		decl: decl,
@@ -46,40 +54,95 @@ func (f *Func) Returns() Vars {
 	return f.returns
 }
 
+// Returns names of the type parameters of a generic func or of the receiver of a method
+// declared on a generic type.
+func (f *Func) TypeParams() []string {
+	f.parse()
+	return f.typeParams
+}
+
+func (f *Func) IsGeneric() bool   { return len(f.TypeParams()) > 0 }
 func (f *Func) Package() *Package { return f.pkg }
 func (f *Func) String() string    { return fullyQualifiedName(f.pkg, f.name) }
 
+// Returns an identifier unique among funcs of the result, methods are qualified with their
+// receiver such as example.com/todo.(*Repo[T]).Create.
+func (f *Func) ID() string {
+	recv := f.Recv()
+
+	if recv == nil {
+		return f.String()
+	}
+
+	receiver := recv.TypeExpr().Render(func(t *Type) string { return t.Name() })
+
+	if recv.TypeExpr().Kind() == ExprKindPointer {
+		receiver = "(" + receiver + ")"
+	}
+
+	return fullyQualifiedName(f.pkg, receiver+"."+f.name)
+}
+
 func (f *Func) parse() {
 	f.lazy.Do(func() {
-		// Process receiver field
-		if f.decl.Recv != nil {
-			f.recv = f.file.parseField(f.decl.Recv.List[0])
+		if f.cached != nil {
+			f.restore()
+			return
 		}
 
-		// Process function parameters
-		if f.decl.Type.Params.List != nil {
-			f.params = make(Vars, len(f.decl.Type.Params.List))
+		f.typeParams = newTypeParams(f.decl.Type.TypeParams)
 
-			for i, field := range f.decl.Type.Params.List {
-				f.params[i] = f.file.parseField(field)
-			}
+		// Process receiver field, type parameters of a generic receiver are declared by it
+		if f.decl.Recv != nil {
+			field := f.decl.Recv.List[0]
+			f.typeParams = append(f.typeParams, receiverTypeParams(field.Type)...)
+			f.recv = f.file.parseField(field, f.typeParams)[0]
+		}
+
+		// Process function parameters, grouped names (from, to uint) are expanded
+		for _, field := range f.decl.Type.Params.List {
+			f.params = append(f.params, f.file.parseField(field, f.typeParams)...)
 		}
 
 		// Process function results
 		if f.decl.Type.Results != nil {
-			f.returns = make(Vars, len(f.decl.Type.Results.List))
-
-			for i, field := range f.decl.Type.Results.List {
-				f.returns[i] = f.file.parseField(field)
+			for _, field := range f.decl.Type.Results.List {
+				f.returns = append(f.returns, f.file.parseField(field, f.typeParams)...)
 			}
 		}
 	})
 }
 
+// Retrieve type parameters names declared by a receiver such as *Repo[T].
+func receiverTypeParams(expr ast.Expr) typeParams {
+	if star, isPointer := expr.(*ast.StarExpr); isPointer {
+		expr = star.X
+	}
+
+	var indices []ast.Expr
+
+	switch t := expr.(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{t.Index}
+	case *ast.IndexListExpr:
+		indices = t.Indices
+	}
+
+	var params typeParams
+
+	for _, index := range indices {
+		if ident, ok := index.(*ast.Ident); ok {
+			params = append(params, ident.Name)
+		}
+	}
+
+	return params
+}
+
 // Checks wether or not this function returns an error.
 func (v Vars) HasError() bool {
 	for _, v := range v {
-		if v.Type().IsError() {
+		if v.Type() != nil && v.Type().IsError() {
 			return true
 		}
 	}
@@ -87,78 +150,158 @@ func (v Vars) HasError() bool {
 	return false
 }
 
-type ResolveResult struct {
-	// TODO: add the list of packages needed to import those funcs maybe?
-	ordered []*Func
-	types   map[string]*Func
-}
+type (
+	ResolveResult struct {
+		ordered []*Dependency
+		types   map[string]*Dependency // Dependencies by instance of the type they provide
+	}
+
+	// Dependency is a function needed to instantiate resolved types. Generic constructors
+	// are instantiated so params and returns reference concrete types.
+	Dependency struct {
+		*Func
+		typeArgs []*TypeExpr
+		params   Vars
+		returns  Vars
+	}
+)
+
+func (d *Dependency) TypeArgs() []*TypeExpr { return d.typeArgs }
+func (d *Dependency) Params() Vars          { return d.params }
+func (d *Dependency) Returns() Vars         { return d.returns }
 
 // Resolve the given types by finding which functions are needed to be called to
 // actually instantiate them. It will recusrsively resolve the functions params to build
-// up the total chain.
-func (fns Funcs) Resolve(types ...*Type) (*ResolveResult, error) {
+// up the total chain. Generic constructors are used when their returned type can be
+// instantiated to match the required one.
+func (fns Funcs) Resolve(types ...*TypeExpr) (*ResolveResult, error) {
 	r := &ResolveResult{
-		types: make(map[string]*Func),
+		types: make(map[string]*Dependency),
 	}
 
 	for _, typ := range types {
-		_, found := r.types[typ.String()]
-
-		// We already know how to resolve this type.
-		if found {
-			continue
+		if _, err := r.resolve(fns, typ); err != nil {
+			return nil, err
 		}
+	}
+
+	return r, nil
+}
+
+func (r *ResolveResult) Dependencies() []*Dependency { return r.ordered }
+
+// Resolve the dependency providing the given type, returns false if none was found.
+func (r *ResolveResult) resolve(fns Funcs, typ *TypeExpr) (bool, error) {
+	// We already know how to resolve this type.
+	if _, found := r.types[typ.Instance()]; found {
+		return true, nil
+	}
+
+	providers := fns.Providers(typ)
+
+	// The first provider wins, Providers may be used to detect ambiguities
+	if len(providers) == 0 {
+		return false, nil
+	}
 
-		for _, f := range fns {
-			for _, ret := range f.Returns() {
-				if ret.Type() == typ {
-					resolved, err := r.resolveFn(fns, f)
+	if err := r.resolveFn(fns, providers[0]); err != nil {
+		return false, err
+	}
+
+	r.types[typ.Instance()] = providers[0]
 
-					if err != nil {
-						return nil, err
-					}
+	return true, nil
+}
 
-					r.types[typ.String()] = resolved
-					// FIXME: refacto needed to remove duplication with resolveFn
-				}
+// Providers returns every function which could be called to instantiate the given type,
+// pointers aside, in declaration order. Generic functions are instantiated accordingly.
+func (fns Funcs) Providers(typ *TypeExpr) []*Dependency {
+	var providers []*Dependency
+
+	for _, f := range fns {
+		for _, ret := range f.Returns() {
+			if dep, matches := instantiate(f, ret, typ); matches {
+				providers = append(providers, dep)
+				break
 			}
 		}
 	}
 
-	return r, nil
+	return providers
 }
 
-func (r *ResolveResult) Funcs() []*Func { return r.ordered }
+func (r *ResolveResult) resolveFn(fns Funcs, dep *Dependency) error {
+	for _, p := range dep.Params() {
+		if p.IsUnsupported() {
+			return diagnostic.Errorf(p.Position(), codeUnsupportedDependency,
+				"dependency of type %s required by %s can not be resolved", p.Expr(), dep.String())
+		}
 
-func (r *ResolveResult) resolveFn(fns Funcs, fn *Func) (*Func, error) {
-	for _, p := range fn.Params() {
-		_, found := r.types[p.Type().String()]
+		found, err := r.resolve(fns, p.TypeExpr())
 
-		if found {
-			continue
+		if err != nil {
+			return err
 		}
 
-		for _, f := range fns {
-			for _, ret := range f.Returns() {
-				if ret.Type() == p.Type() {
-					resolved, err := r.resolveFn(fns, f)
+		if !found {
+			return diagnostic.Errorf(p.Position(), codeUnresolvedDependency,
+				"could not find a valid constructor for %s required by %s", p.TypeExpr().Instance(), dep.String())
+		}
+	}
 
-					if err != nil {
-						return nil, err
-					}
+	r.ordered = append(r.ordered, dep)
 
-					r.types[p.Type().String()] = resolved
-					found = true
-				}
-			}
-		}
+	return nil
+}
 
-		if !found {
-			return nil, fmt.Errorf("could not find a valid constructor for %s", p.Type().String())
+// Instantiate the given function if the returned var provides the required type, pointers
+// aside. Type arguments of a generic function are inferred from the required type.
+func instantiate(fn *Func, ret *Var, typ *TypeExpr) (*Dependency, bool) {
+	// Methods can not be called without an instance
+	if fn.Recv() != nil {
+		return nil, false
+	}
+
+	bindings := make(map[string]*TypeExpr)
+
+	if !ret.TypeExpr().deref().unify(typ.deref(), bindings) {
+		return nil, false
+	}
+
+	dep := &Dependency{
+		Func:    fn,
+		params:  substituteVars(fn.Params(), bindings),
+		returns: substituteVars(fn.Returns(), bindings),
+	}
+
+	// Every type parameter must be inferred for the function to be called
+	for _, name := range fn.TypeParams() {
+		arg, bound := bindings[name]
+
+		if !bound {
+			return nil, false
 		}
+
+		dep.typeArgs = append(dep.typeArgs, arg)
 	}
 
-	r.ordered = append(r.ordered, fn)
+	return dep, true
+}
+
+func substituteVars(vars Vars, bindings map[string]*TypeExpr) Vars {
+	if len(bindings) == 0 {
+		return vars
+	}
+
+	result := make(Vars, len(vars))
+
+	for i, v := range vars {
+		result[i] = &Var{
+			Decl:   v.Decl,
+			expr:   v.expr.substitute(bindings),
+			source: v.source,
+		}
+	}
 
-	return fn, nil
+	return result
 }
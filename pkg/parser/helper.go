@@ -1,8 +1,97 @@
 package parser
 
-import "go/types"
+import (
+	"go/token"
+	"go/types"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
 
 // Checks if the given typename is a builtin one.
 func IsBuiltin(typeName string) bool {
 	return types.Universe.Lookup(typeName) != nil
 }
+
+// Parses a position formatted as "file:line:col" (or "file:line", "file") as returned by
+// the packages loader.
+func parsePosition(s string) token.Position {
+	var (
+		pos   token.Position
+		parts []int
+	)
+
+	// Read numbers from the right since the filename may contain colons
+	for len(parts) < 2 {
+		idx := strings.LastIndex(s, ":")
+
+		if idx < 0 {
+			break
+		}
+
+		n, err := strconv.Atoi(s[idx+1:])
+
+		if err != nil {
+			break
+		}
+
+		parts = append([]int{n}, parts...)
+		s = s[:idx]
+	}
+
+	if s == "" || s == "-" {
+		return pos
+	}
+
+	pos.Filename = s
+
+	if len(parts) > 0 {
+		pos.Line = parts[0]
+	}
+
+	if len(parts) > 1 {
+		pos.Column = parts[1]
+	}
+
+	return pos
+}
+
+// Calls fn for every index up to n, using as many goroutines as available processors.
+func parallel(n int, fn func(int)) {
+	var (
+		wg      sync.WaitGroup
+		workers = make(chan struct{}, runtime.GOMAXPROCS(0))
+	)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		workers <- struct{}{}
+
+		go func(i int) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+
+			fn(i)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// Checks if the given package path matches a package pattern, where "..." matches any
+// string, as understood by the go command.
+func MatchPackagePattern(pattern, pkgPath string) bool {
+	// net/... matches net too
+	if strings.HasSuffix(pattern, "/...") && pkgPath == strings.TrimSuffix(pattern, "/...") {
+		return true
+	}
+
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\.\.\.`, `.*`) + "$"
+	matched, _ := regexp.MatchString(expr, pkgPath)
+
+	return matched
+}
@@ -1,21 +1,43 @@
 package parser
 
 import (
+	"go/token"
 	"strings"
 )
 
 // Represents a single package and act as a registry of declarations for easy parsing.
+// Directives found in the package documentation are available on the package itself.
 type Package struct {
-	name string
+	*Decl
+	name string // Declared name, empty if the package was never loaded
 	path string
 }
 
-func newPackage(path string) *Package {
+func newPackage(fset *token.FileSet, prefix, path string) *Package {
 	return &Package{
-		name: path[strings.LastIndex(path, "/")+1:],
+		Decl: newDeclaration(fset, prefix, token.NoPos, nil),
 		path: path,
 	}
 }
 
-func (p *Package) Name() string { return p.name }
 func (p *Package) Path() string { return p.path }
+
+// Name of the package, the last element of its path if it was never loaded.
+func (p *Package) Name() string {
+	if p.name != "" {
+		return p.name
+	}
+
+	return p.path[strings.LastIndex(p.path, "/")+1:]
+}
+
+// DeclaredName returns the name of the package clause, which may differ from the last
+// element of its path, or an empty string if the package was never loaded. Parsed
+// packages and the ones they import are loaded.
+func (p *Package) DeclaredName() string { return p.name }
+
+// Checks if the package was parsed, as opposed to packages only referenced by imports.
+func (p *Package) IsDeclared() bool {
+	position := p.Position()
+	return position.IsValid()
+}
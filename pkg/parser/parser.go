@@ -1,88 +1,403 @@
 package parser
 
 import (
-	"fmt"
+	"errors"
 	"go/token"
 	"path"
+	"path/filepath"
+	"strings"
 
+	"github.com/YuukanOO/ease/pkg/diagnostic"
 	"golang.org/x/tools/go/packages"
 )
 
+const codePackageLoad = "package-load"
+
 type (
 	// Parser used to process packages names and extract information from them.
 	Parser interface {
 		// Parse given package names.
 		Parse(packageNames ...string) (Result, error)
+		// Directives returns schemas of every directive understood by extensions.
+		Directives() []*DirectiveSchema
 	}
 
+	// Extension visits the parse result to extract additional information. Problems should be
+	// appended to the result diagnostics, a returned error is reported as a diagnostic too.
 	Extension interface {
 		Visit(Result) error
 	}
 
+	// Configures how packages are loaded, the zero value loads them from the current
+	// directory without any cache.
+	Config struct {
+		Cache           *Cache   // Restores declarations of unchanged packages from it, nil disables it
+		Dir             string   // Directory in which package patterns are resolved, the current one if empty
+		BuildTags       []string // Additional build tags to consider when loading packages
+		DirectivePrefix string   // Prefix of directives, DefaultDirectivePrefix if empty
+		Exclude         []string // Patterns of packages which must not be parsed, such as example.com/mod/internal/...
+		Outputs         []string // Directories where code is generated, packages in them are not parsed since they will be overwritten
+	}
+
 	parser struct {
 		extensions []Extension
+		directives []*DirectiveSchema
+		config     Config
 	}
 )
 
+const (
+	listMode = packages.NeedName | packages.NeedFiles | packages.NeedModule
+	loadMode = packages.NeedSyntax | packages.NeedTypes | packages.NeedModule | packages.NeedName
+)
+
 // New creates a new Parser.
 func New(extensions ...Extension) Parser {
-	return &parser{
+	return NewWithCache(nil, extensions...)
+}
+
+// NewWithCache creates a new Parser which restores declarations of unchanged packages from
+// the given cache instead of loading and parsing them again. A nil cache disables it.
+func NewWithCache(cache *Cache, extensions ...Extension) Parser {
+	return NewWithConfig(Config{Cache: cache}, extensions...)
+}
+
+// NewWithConfig creates a new Parser which loads packages as configured.
+func NewWithConfig(config Config, extensions ...Extension) Parser {
+	p := &parser{
 		extensions: extensions,
+		config:     config,
 	}
+
+	for _, extension := range extensions {
+		if provider, ok := extension.(DirectivesProvider); ok {
+			p.directives = append(p.directives, provider.Directives()...)
+		}
+	}
+
+	return p
 }
 
-func (p *parser) Parse(packageNames ...string) (Result, error) {
-	fset := token.NewFileSet()
-	pkgs, err := packages.Load(&packages.Config{
+func (c Config) prefix() string {
+	if c.DirectivePrefix == "" {
+		return DefaultDirectivePrefix
+	}
+
+	return c.DirectivePrefix
+}
+
+// Removes excluded packages from the given ones.
+func (c Config) filter(pkgs []*packages.Package) []*packages.Package {
+	if len(c.Exclude) == 0 {
+		return pkgs
+	}
+
+	kept := pkgs[:0]
+
+	for _, pkg := range pkgs {
+		if !c.excluded(pkg.PkgPath) {
+			kept = append(kept, pkg)
+		}
+	}
+
+	return kept
+}
+
+func (c Config) excluded(pkgPath string) bool {
+	for _, pattern := range c.Exclude {
+		if MatchPackagePattern(pattern, pkgPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *parser) packagesConfig(fset *token.FileSet, mode packages.LoadMode) *packages.Config {
+	config := &packages.Config{
 		Fset: fset,
-		Mode: packages.NeedSyntax | packages.NeedTypes | packages.NeedModule | packages.NeedName,
-	}, packageNames...)
+		Mode: mode,
+		Dir:  p.config.Dir,
+	}
 
-	if err != nil {
-		return nil, err
+	if len(p.config.BuildTags) > 0 {
+		config.BuildFlags = []string{"-tags=" + strings.Join(p.config.BuildTags, ",")}
 	}
 
-	var generatedModulePath string
-	mod := findMainModule(pkgs)
+	return config
+}
+
+func (p *parser) Directives() []*DirectiveSchema { return p.directives }
+
+func (p *parser) Parse(packageNames ...string) (Result, error) {
+	var (
+		fset    = token.NewFileSet()
+		pkgs    []*packages.Package
+		entries = make(map[string]*cachedPackage) // Cache hits by package path
+		hashes  = make(map[string]string)         // Hashes of packages which must be cached
+		err     error
+	)
 
-	if mod != nil {
-		generatedModulePath = path.Join(mod.Path, "generated") // FIXME: no hardcoded value!
+	if p.config.Cache == nil {
+		pkgs, err = packages.Load(p.packagesConfig(fset, loadMode), packageNames...)
+	} else {
+		pkgs, err = p.loadCached(fset, entries, hashes, packageNames)
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
-	result := newResult()
+	pkgs = p.config.filter(pkgs)
 
-	// And process each package files
+	var (
+		generated = p.config.generatedPackages(pkgs)
+		result    = newResult(fset, p.config.prefix())
+		files     = make([][]*FileResult, len(pkgs))
+		errs      = make([]error, len(pkgs))
+	)
+
+	// Imports are resolved by name so names must be known before files are parsed
 	for _, pkg := range pkgs {
-		if len(pkg.Errors) > 0 {
-			fmt.Println(pkg.Errors) // FIXME: replace with logger call
+		if generated[pkg.PkgPath] {
 			continue
 		}
 
-		// Skip the generated package
-		if pkg.PkgPath == generatedModulePath {
-			continue
+		if entry := entries[pkg.PkgPath]; entry != nil {
+			result.setNames(entry.Names)
+		} else {
+			result.setNames(declaredNames(pkg))
 		}
+	}
 
-		for _, file := range pkg.Syntax {
-			if err = result.ParseFile(pkg.PkgPath, file); err != nil {
-				return nil, err
-			}
+	// Packages are processed concurrently but registered in order to keep a deterministic result
+	parallel(len(pkgs), func(i int) {
+		// Skip generated packages, their errors do not matter since they will be overwritten
+		if !generated[pkgs[i].PkgPath] {
+			files[i], errs[i] = result.parsePackage(pkgs[i], entries[pkgs[i].PkgPath])
 		}
+	})
+
+	if err = errors.Join(errs...); err != nil {
+		return nil, err
 	}
 
-	// And finally, visit each extension
+	for i, pkgFiles := range files {
+		// Packages with errors must be loaded again on the next run to report them
+		if len(pkgs[i].Errors) > 0 {
+			delete(hashes, pkgs[i].PkgPath)
+		}
+
+		for _, file := range pkgFiles {
+			file.Register()
+		}
+	}
+
+	// Encoding is done sequentially since it may register referenced types
+	cached := make([]*cachedPackage, 0, len(hashes))
+
+	for i := range pkgs {
+		if hash, found := hashes[pkgs[i].PkgPath]; found {
+			cached = append(cached, result.encodePackage(result.Package(pkgs[i].PkgPath), hash, declaredNames(pkgs[i])))
+		}
+	}
+
+	// Failing to write the cache only means packages will be parsed again next time
+	parallel(len(cached), func(i int) {
+		if err := p.config.Cache.store(cached[i]); err != nil {
+			result.diagnostics.Add(diagnostic.Warnf(token.Position{}, codeCache,
+				"could not cache package %s: %v", cached[i].Path, err))
+		}
+	})
+
+	result.reportDirectivesErrors()
+	result.validateDirectives(p.directives)
+
+	// And finally, visit each extension, errors are reported as diagnostics so that every
+	// problem can be reported at once
 	for _, extension := range p.extensions {
-		if err = extension.Visit(result); err != nil {
+		result.diagnostics.Report(extension.Visit(result))
+	}
+
+	return result, nil
+}
+
+// Parse files of the given package or restore them from the given cache entry if any.
+// Load errors are reported as diagnostics.
+func (r *result) parsePackage(pkg *packages.Package, entry *cachedPackage) ([]*FileResult, error) {
+	if entry != nil {
+		return []*FileResult{r.restorePackage(entry)}, nil
+	}
+
+	if len(pkg.Errors) > 0 {
+		positioned := false
+
+		for _, e := range pkg.Errors {
+			positioned = positioned || e.Pos != ""
+		}
+
+		for _, e := range pkg.Errors {
+			// go list repeats errors of the parser and type checker without their position
+			if e.Kind == packages.ListError && e.Pos == "" && positioned {
+				continue
+			}
+
+			r.diagnostics.Add(diagnostic.Errorf(parsePosition(e.Pos), codePackageLoad, "%s", e.Msg))
+		}
+
+		return nil, nil
+	}
+
+	files := make([]*FileResult, len(pkg.Syntax))
+
+	for i, file := range pkg.Syntax {
+		fileResult, err := r.ParseFile(pkg.PkgPath, file)
+
+		if err != nil {
 			return nil, err
 		}
+
+		files[i] = fileResult
 	}
 
-	return result, nil
+	return files, nil
+}
+
+// Returns declared names of the given package and of the ones it imports by path.
+func declaredNames(pkg *packages.Package) map[string]string {
+	names := make(map[string]string)
+
+	if pkg.Name != "" {
+		names[pkg.PkgPath] = pkg.Name
+	}
+
+	if pkg.Types != nil {
+		for _, imported := range pkg.Types.Imports() {
+			names[imported.Path()] = imported.Name()
+		}
+	}
+
+	return names
+}
+
+func (r *result) setNames(names map[string]string) {
+	for pkgPath, name := range names {
+		r.Package(pkgPath).name = name
+	}
+}
+
+// Lists packages matching the given names and load only the ones which could not be
+// found in the cache. Cache hits are added to entries and hashes of loaded packages are
+// added to hashes. Packages are returned in the order they were listed.
+func (p *parser) loadCached(
+	fset *token.FileSet,
+	entries map[string]*cachedPackage,
+	hashes map[string]string,
+	packageNames []string,
+) ([]*packages.Package, error) {
+	pkgs, err := packages.Load(p.packagesConfig(nil, listMode), packageNames...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Do not load excluded packages at all
+	pkgs = p.config.filter(pkgs)
+
+	var (
+		misses     []string
+		generated  = p.config.generatedPackages(pkgs)
+		pkgHashes  = make([]string, len(pkgs))
+		pkgEntries = make([]*cachedPackage, len(pkgs))
+	)
+
+	// Hashing requires reading every file so do it concurrently
+	parallel(len(pkgs), func(i int) {
+		if generated[pkgs[i].PkgPath] || len(pkgs[i].Errors) > 0 {
+			return
+		}
+
+		hash, err := p.config.Cache.hash(p.config.prefix(), pkgs[i].PkgPath, pkgs[i].GoFiles)
+
+		if err != nil {
+			return
+		}
+
+		pkgHashes[i] = hash
+		pkgEntries[i], _ = p.config.Cache.load(pkgs[i].PkgPath, hash)
+	})
+
+	for i, pkg := range pkgs {
+		switch {
+		case generated[pkg.PkgPath]:
+		case pkgEntries[i] != nil:
+			entries[pkg.PkgPath] = pkgEntries[i]
+		case pkgHashes[i] != "":
+			misses = append(misses, pkg.PkgPath)
+			hashes[pkg.PkgPath] = pkgHashes[i]
+		default:
+			misses = append(misses, pkg.PkgPath)
+		}
+	}
+
+	if len(misses) == 0 {
+		return pkgs, nil
+	}
+
+	loaded, err := packages.Load(p.packagesConfig(fset, loadMode), misses...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	byPath := make(map[string]*packages.Package, len(loaded))
+
+	for _, pkg := range loaded {
+		byPath[pkg.PkgPath] = pkg
+	}
+
+	for i, pkg := range pkgs {
+		if full, found := byPath[pkg.PkgPath]; found {
+			pkgs[i] = full
+		}
+	}
+
+	return pkgs, nil
+}
+
+// Returns paths of the main module packages located in output directories, they should
+// not be parsed.
+func (c Config) generatedPackages(pkgs []*packages.Package) map[string]bool {
+	mod := findMainModule(pkgs)
+	generated := make(map[string]bool, len(c.Outputs))
+
+	if mod == nil || mod.Dir == "" {
+		return generated
+	}
+
+	for _, output := range c.Outputs {
+		dir, err := filepath.Abs(output)
+
+		if err != nil {
+			continue
+		}
+
+		rel, err := filepath.Rel(mod.Dir, dir)
+
+		// Outside of the main module, it can not contain any of its packages
+		if err != nil || (rel != "." && !filepath.IsLocal(rel)) {
+			continue
+		}
+
+		generated[path.Join(mod.Path, filepath.ToSlash(rel))] = true
+	}
+
+	return generated
 }
 
 func findMainModule(pkgs []*packages.Package) *packages.Module {
 	for _, pkg := range pkgs {
-		if pkg.Module.Main {
+		if pkg.Module != nil && pkg.Module.Main {
 			return pkg.Module
 		}
 	}
@@ -1,8 +1,14 @@
 package parser_test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/YuukanOO/ease/pkg/diagnostic"
 	"github.com/YuukanOO/ease/pkg/parser"
 )
 
@@ -16,3 +22,576 @@ func TestParser(t *testing.T) {
 		}
 	})
 }
+
+func TestDeclarationPositions(t *testing.T) {
+	t.Run("should keep the position of parsed declarations", func(t *testing.T) {
+		p := parser.New()
+		result, err := p.Parse("github.com/YuukanOO/ease/pkg/parser/testdata")
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, fn := range result.Funcs() {
+			if fn.Name() != "NewTestService" {
+				continue
+			}
+
+			pos := fn.Position()
+
+			if filepath.Base(pos.Filename) != "service.go" || pos.Line != 17 {
+				t.Errorf("expected NewTestService to be declared at service.go:17, got %s", pos)
+			}
+
+			return
+		}
+
+		t.Error("expected NewTestService to be parsed")
+	})
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Run("should report each load error once at its position", func(t *testing.T) {
+		for _, cache := range []*parser.Cache{nil, parser.NewCache(t.TempDir(), "test")} {
+			result, err := parser.NewWithCache(cache).Parse("github.com/YuukanOO/ease/pkg/parser/testdata/broken")
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			diagnostics := result.Diagnostics().Items()
+
+			if len(diagnostics) != 1 {
+				t.Fatalf("expected 1 diagnostic, got %d: %v", len(diagnostics), diagnostics)
+			}
+
+			if d := diagnostics[0]; d.Code != "package-load" || filepath.Base(d.Position.Filename) != "broken.go" || d.Position.Line != 4 {
+				t.Errorf("expected a package-load error at broken.go:4, got %v", d)
+			}
+		}
+	})
+}
+
+func TestPackageNames(t *testing.T) {
+	t.Run("should use declared names of loaded packages even when restored from the cache", func(t *testing.T) {
+		cache := parser.NewCache(t.TempDir(), "test")
+
+		for i := 0; i < 2; i++ {
+			result, err := parser.NewWithCache(cache).Parse("github.com/YuukanOO/ease/pkg/parser/testdata/renamed")
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			names := make(map[string]string)
+
+			for _, pkg := range result.Packages() {
+				names[pkg.Path()] = pkg.Name() + "|" + pkg.DeclaredName()
+			}
+
+			if expected := map[string]string{
+				"github.com/YuukanOO/ease/pkg/parser/testdata/renamed": "actual|actual",
+				"gopkg.in/yaml.v3": "yaml|yaml",
+			}; !reflect.DeepEqual(names, expected) {
+				t.Errorf("expected names %v, got %v", expected, names)
+			}
+		}
+	})
+}
+
+type knownDirectiveExtension struct{}
+
+func (knownDirectiveExtension) Visit(parser.Result) error { return nil }
+
+func (knownDirectiveExtension) Directives() []*parser.DirectiveSchema {
+	return []*parser.DirectiveSchema{
+		{
+			Name:    "known",
+			Targets: parser.DeclKindFunc,
+			Params:  []*parser.ParamSchema{{Name: "count", Type: parser.ValueTypeInt}},
+		},
+	}
+}
+
+func TestDirectivesValidation(t *testing.T) {
+	t.Run("should warn about unknown directives and params", func(t *testing.T) {
+		p := parser.New(knownDirectiveExtension{})
+		result, err := p.Parse("github.com/YuukanOO/ease/pkg/parser/testdata")
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		diagnostics := result.Diagnostics().Items()
+
+		if len(diagnostics) != 2 {
+			t.Fatalf("expected 2 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+		}
+
+		for _, d := range diagnostics {
+			if d.Severity != diagnostic.SeverityWarning {
+				t.Errorf("expected a warning, got %s", d)
+			}
+		}
+
+		if diagnostics[0].Code != "unknown-directive-param" || diagnostics[1].Code != "unknown-directive" {
+			t.Errorf("expected unknown param and directive warnings, got %v", diagnostics)
+		}
+	})
+}
+
+func TestFuncParams(t *testing.T) {
+	p := parser.New()
+	result, err := p.Parse("github.com/YuukanOO/ease/pkg/parser/testdata")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	funcs := make(map[string]*parser.Func)
+
+	for _, fn := range result.Funcs() {
+		funcs[fn.Name()] = fn
+	}
+
+	t.Run("should expand grouped params and handle variadic ones", func(t *testing.T) {
+		params := funcs["Move"].Params()
+
+		if len(params) != 4 {
+			t.Fatalf("expected 4 params, got %d", len(params))
+		}
+
+		for i, name := range []string{"ctx", "from", "to", "opts"} {
+			if params[i].Name() != name {
+				t.Errorf("expected param %d to be named %s, got %s", i, name, params[i].Name())
+			}
+		}
+
+		if params[2].Type().String() != "uint" {
+			t.Errorf("expected to param to be an uint, got %s", params[2].Type())
+		}
+
+		if !params[3].IsVariadic() || !params[3].IsSlice() || params[3].Type().String() != "string" {
+			t.Errorf("expected opts to be a variadic string, got %s", params[3].Expr())
+		}
+	})
+
+	t.Run("should represent every type expression", func(t *testing.T) {
+		params := funcs["Shapes"].Params()
+		expected := []struct {
+			expr string
+			kind parser.VarKind
+		}{
+			{"func(int) error", parser.VarKindFunc},
+			{"<-chan string", parser.VarKindChan},
+			{"[4]byte", parser.VarKindArray},
+			{"struct{Name string}", parser.VarKindStruct},
+		}
+
+		for i, e := range expected {
+			if params[i].Expr() != e.expr {
+				t.Errorf("expected param %d expression to be %s, got %s", i, e.expr, params[i].Expr())
+			}
+
+			if params[i].Kind()&e.kind == 0 {
+				t.Errorf("expected param %d to have kind %d, got %d", i, e.kind, params[i].Kind())
+			}
+		}
+
+		if !params[0].IsUnsupported() || params[2].IsUnsupported() {
+			t.Error("expected func params to be unsupported and arrays to be supported")
+		}
+	})
+	t.Run("should represent nested composite types", func(t *testing.T) {
+		params := funcs["Nested"].Params()
+		expected := []string{
+			"map[string][]*github.com/YuukanOO/ease/pkg/parser/testdata.TestModel",
+			"[][2]int",
+			"**github.com/YuukanOO/ease/pkg/parser/testdata.TestModel",
+			"chan<- map[int]string",
+		}
+
+		for i, e := range expected {
+			if params[i].TypeExpr().String() != e {
+				t.Errorf("expected param %d expression to be %s, got %s", i, e, params[i].TypeExpr())
+			}
+		}
+
+		index := params[0].TypeExpr()
+
+		if index.Kind() != parser.ExprKindMap || index.Key().Type().String() != "string" {
+			t.Errorf("expected index to be a map with string keys, got %s", index)
+		}
+
+		if params[0].Type().Name() != "TestModel" || params[2].TypeExpr().PointerDepth() != 2 {
+			t.Error("expected the underlying type to be found through nested types")
+		}
+
+		rendered := index.Render(func(t *parser.Type) string {
+			if t.IsBuiltin() {
+				return t.Name()
+			}
+
+			return "td." + t.Name()
+		})
+
+		if rendered != "map[string][]*td.TestModel" {
+			t.Errorf("expected the qualifier to be used for named types, got %s", rendered)
+		}
+	})
+}
+
+func TestFields(t *testing.T) {
+	result, err := parser.New().Parse("github.com/YuukanOO/ease/pkg/parser/testdata")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	types := make(map[string]*parser.Type)
+
+	for _, typ := range result.Types() {
+		types[typ.Name()] = typ
+	}
+
+	t.Run("should expose struct fields with their tags", func(t *testing.T) {
+		var fields []string
+
+		for _, field := range types["TestTaggedModel"].Fields() {
+			fields = append(fields, fmt.Sprintf("%s %s %s", field.Name(), field.Expr(), field.Tag()))
+		}
+
+		expected := []string{
+			"TestModel *TestModel ",
+			`ID int json:"id,omitempty" db:"pk"`,
+			`Title string json:",omitempty"`,
+			`Secret string json:"-"`,
+			`Dash string json:"-,"`,
+			"Lat float64 ",
+			"Lng float64 ",
+			"internal bool ",
+		}
+
+		if !reflect.DeepEqual(fields, expected) {
+			t.Errorf("expected fields %v, got %v", expected, fields)
+		}
+
+		embedded := types["TestTaggedModel"].Fields()[0]
+
+		if !embedded.IsEmbedded() || !embedded.IsPointer() || embedded.Type() != types["TestModel"] {
+			t.Errorf("expected an embedded pointer to TestModel, got %s", embedded.TypeExpr())
+		}
+	})
+
+	t.Run("should not expose fields of types only referenced", func(t *testing.T) {
+		mutex := types["TestService"].Fields()[0].Type()
+
+		if fields := mutex.Fields(); mutex.IsDeclared() || fields != nil {
+			t.Errorf("expected referenced types to have no fields, got %v", fields)
+		}
+	})
+}
+
+func TestResolve(t *testing.T) {
+	p := parser.New()
+	result, err := p.Parse("github.com/YuukanOO/ease/pkg/parser/testdepdata")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	funcs := make(map[string]*parser.Func)
+
+	for _, fn := range result.Funcs() {
+		funcs[fn.Name()] = fn
+	}
+
+	t.Run("should instantiate generic constructors", func(t *testing.T) {
+		resolved, err := result.Funcs().Resolve(funcs["NewUserService"].Returns()[0].TypeExpr())
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		deps := make(map[string]*parser.Dependency)
+
+		for _, dep := range resolved.Dependencies() {
+			deps[dep.Name()] = dep
+		}
+
+		// LoadOptions, OpenDatabase, NewRepository, NewCache and NewUserService
+		if len(deps) != 5 {
+			t.Fatalf("expected 5 dependencies, got %d", len(deps))
+		}
+
+		repository := deps["NewRepository"]
+
+		if len(repository.TypeArgs()) != 1 || repository.TypeArgs()[0].String() != "github.com/YuukanOO/ease/pkg/parser/testdepdata.Service" {
+			t.Errorf("expected the repository to be instantiated with Service, got %v", repository.TypeArgs())
+		}
+
+		cache := deps["NewCache"]
+		expected := "*github.com/YuukanOO/ease/pkg/parser/testdepdata.Cache[string, *github.com/YuukanOO/ease/pkg/parser/testdepdata.OtherService]"
+
+		if len(cache.TypeArgs()) != 2 || cache.Returns()[0].TypeExpr().String() != expected {
+			t.Errorf("expected the cache return to be instantiated, got %s", cache.Returns()[0].TypeExpr())
+		}
+	})
+
+	t.Run("should list providers of a type", func(t *testing.T) {
+		providers := result.Funcs().Providers(funcs["NewService"].Params()[0].TypeExpr())
+
+		if len(providers) != 1 || providers[0].Name() != "NewLogger" {
+			t.Errorf("expected NewLogger to be the only provider, got %v", providers)
+		}
+
+		if providers := result.Funcs().Providers(funcs["NewNotifier"].Params()[0].TypeExpr()); len(providers) != 0 {
+			t.Errorf("expected no provider for Mailer, got %v", providers)
+		}
+	})
+
+	t.Run("should report dependencies without constructor", func(t *testing.T) {
+		_, err := result.Funcs().Resolve(funcs["NewNotifier"].Returns()[0].TypeExpr())
+
+		var d *diagnostic.Diagnostic
+
+		if !errors.As(err, &d) || d.Code != "unresolved-dependency" {
+			t.Fatalf("expected an unresolved dependency diagnostic, got %v", err)
+		}
+	})
+}
+
+func TestCache(t *testing.T) {
+	t.Run("should restore the same declarations from the cache", func(t *testing.T) {
+		cache := parser.NewCache(t.TempDir(), "test")
+		describe := func() []string {
+			result, err := parser.NewWithCache(cache).Parse("github.com/YuukanOO/ease/pkg/parser/testdata")
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var lines []string
+
+			for _, fn := range result.Funcs() {
+				line := fmt.Sprintf("%s %s", fn, fn.Position())
+
+				for _, param := range fn.Params() {
+					line += fmt.Sprintf(" %s:%s", param.Name(), param.TypeExpr())
+				}
+
+				for _, directive := range fn.AllDirectives() {
+					line += fmt.Sprintf(" @%s%v", directive.Name, directive.Keys())
+				}
+
+				lines = append(lines, line)
+			}
+
+			for _, typ := range result.Types() {
+				// Referenced types are registered in the order they are restored
+				if !typ.IsDeclared() {
+					continue
+				}
+
+				line := fmt.Sprintf("%s %s", typ, typ.Position())
+
+				for _, field := range typ.Fields() {
+					line += fmt.Sprintf(" %s:%s:%q:%t", field.Name(), field.TypeExpr(), field.Tag(), field.IsEmbedded())
+				}
+
+				lines = append(lines, line)
+			}
+
+			return lines
+		}
+
+		parsed, restored := describe(), describe()
+
+		if !reflect.DeepEqual(parsed, restored) {
+			t.Errorf("expected restored declarations to be the same as parsed ones, got\n%v\nand\n%v", parsed, restored)
+		}
+	})
+}
+
+func TestConcurrentParse(t *testing.T) {
+	t.Run("should register declarations in a deterministic order", func(t *testing.T) {
+		cache := parser.NewCache(t.TempDir(), "test")
+		names := func(p parser.Parser) []string {
+			result, err := p.Parse(
+				"github.com/YuukanOO/ease/pkg/parser/testdata",
+				"github.com/YuukanOO/ease/pkg/parser/testdepdata",
+			)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var names []string
+
+			for _, fn := range result.Funcs() {
+				names = append(names, fn.String())
+			}
+
+			return names
+		}
+
+		expected := names(parser.New())
+
+		// Once without and once with every package cached
+		for i := 0; i < 2; i++ {
+			if got := names(parser.NewWithCache(cache)); !reflect.DeepEqual(expected, got) {
+				t.Errorf("expected funcs to be registered in the same order, got\n%v\nand\n%v", expected, got)
+			}
+		}
+	})
+}
+
+func TestConfig(t *testing.T) {
+	t.Run("should not parse excluded packages", func(t *testing.T) {
+		result, err := parser.NewWithConfig(parser.Config{
+			Exclude: []string{"github.com/YuukanOO/ease/pkg/parser/testdep..."},
+		}).Parse("github.com/YuukanOO/ease/pkg/parser/...")
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, fn := range result.Funcs() {
+			if fn.Package().Path() == "github.com/YuukanOO/ease/pkg/parser/testdepdata" {
+				t.Errorf("expected %s to be excluded", fn)
+			}
+		}
+	})
+
+	t.Run("should not parse packages of output directories", func(t *testing.T) {
+		for _, cache := range []*parser.Cache{nil, parser.NewCache(t.TempDir(), "test")} {
+			result, err := parser.NewWithConfig(parser.Config{
+				Cache:   cache,
+				Outputs: []string{"testdata/broken"},
+			}).Parse("github.com/YuukanOO/ease/pkg/parser/testdata/broken")
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diagnostics := result.Diagnostics().Items(); len(diagnostics) != 0 {
+				t.Errorf("expected the output directory to be skipped, got %v", diagnostics)
+			}
+		}
+	})
+
+	t.Run("should only read directives with the configured prefix", func(t *testing.T) {
+		for _, cache := range []*parser.Cache{nil, parser.NewCache(t.TempDir(), "test")} {
+			result, err := parser.NewWithConfig(parser.Config{
+				Cache:           cache,
+				DirectivePrefix: "other",
+			}).Parse("github.com/YuukanOO/ease/pkg/parser/testdata")
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, fn := range result.Funcs() {
+				if fn.Name() == "Annotated" && len(fn.AllDirectives()) > 0 {
+					t.Errorf("expected ease directives to be ignored, got %v", fn.AllDirectives())
+				}
+			}
+		}
+	})
+}
+
+func TestMatchPackagePattern(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		path    string
+		matches bool
+	}{
+		{"example.com/mod", "example.com/mod", true},
+		{"example.com/mod", "example.com/mod/sub", false},
+		{"example.com/mod/...", "example.com/mod", true},
+		{"example.com/mod/...", "example.com/mod/sub/pkg", true},
+		{"example.com/mod/...", "example.com/module", false},
+		{"example.com/.../internal", "example.com/mod/internal", true},
+	} {
+		if got := parser.MatchPackagePattern(test.pattern, test.path); got != test.matches {
+			t.Errorf("expected %s to match %s: %t, got %t", test.pattern, test.path, test.matches, got)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	t.Run("should build the same document from parsed and cached declarations", func(t *testing.T) {
+		cache := parser.NewCache(t.TempDir(), "test")
+		inspect := func() []byte {
+			result, err := parser.NewWithCache(cache).Parse("github.com/YuukanOO/ease/pkg/parser/testdata")
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			data, err := json.Marshal(parser.Inspect(result))
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			return data
+		}
+
+		parsed, restored := inspect(), inspect()
+
+		if string(parsed) != string(restored) {
+			t.Errorf("expected the same document, got\n%s\nand\n%s", parsed, restored)
+		}
+	})
+
+	t.Run("should only describe declared packages and types", func(t *testing.T) {
+		result, err := parser.New().Parse("github.com/YuukanOO/ease/pkg/parser/testdata")
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		doc := parser.Inspect(result)
+
+		if doc.Version != parser.DocumentVersion {
+			t.Errorf("expected version %d, got %d", parser.DocumentVersion, doc.Version)
+		}
+
+		if len(doc.Packages) != 1 || doc.Packages[0].Path != "github.com/YuukanOO/ease/pkg/parser/testdata" {
+			t.Errorf("expected only the parsed package, got %v", doc.Packages)
+		}
+
+		for _, typ := range doc.Types {
+			if typ.Package != "github.com/YuukanOO/ease/pkg/parser/testdata" {
+				t.Errorf("expected only declared types, got %s", typ.ID)
+			}
+		}
+	})
+
+	t.Run("should identify methods by their receiver", func(t *testing.T) {
+		result, err := parser.New().Parse("github.com/YuukanOO/ease/pkg/parser/testdata")
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, fn := range parser.Inspect(result).Funcs {
+			if fn.Name != "GetByID" {
+				continue
+			}
+
+			if expected := "github.com/YuukanOO/ease/pkg/parser/testdata.(*TestService).GetByID"; fn.ID != expected {
+				t.Errorf("expected id %s, got %s", expected, fn.ID)
+			}
+
+			if fn.Recv == nil || fn.Recv.Type.Kind != "pointer" || fn.Recv.Type.Elem.Name != "TestService" {
+				t.Errorf("expected a pointer receiver on TestService, got %v", fn.Recv)
+			}
+
+			return
+		}
+
+		t.Error("expected GetByID to be described")
+	})
+}
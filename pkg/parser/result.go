@@ -2,10 +2,12 @@ package parser
 
 import (
 	"go/ast"
+	"go/token"
+	"go/types"
 	"strings"
 
 	"github.com/YuukanOO/ease/pkg/collection"
-	"github.com/YuukanOO/ease/pkg/flag"
+	"github.com/YuukanOO/ease/pkg/diagnostic"
 )
 
 type (
@@ -13,21 +15,28 @@ type (
 		Packages() []*Package
 		Types() []*Type
 		Funcs() Funcs
+		Diagnostics() *diagnostic.Diagnostics // Problems reported by the parser and its extensions
 	}
 
 	// result of the parsing operation for a multitude of packages.
 	result struct {
-		pkgs  *collection.Set[*Package]
-		types *collection.Set[*Type]
-		funcs *collection.Set[*Func]
+		fset        *token.FileSet
+		prefix      string // Prefix of directives
+		pkgs        *collection.Set[*Package]
+		types       *collection.Set[*Type]
+		funcs       *collection.Set[*Func]
+		diagnostics *diagnostic.Diagnostics
 	}
 )
 
-func newResult() *result {
+func newResult(fset *token.FileSet, prefix string) *result {
 	return &result{
-		pkgs:  collection.NewSet[*Package](),
-		types: collection.NewSet[*Type](),
-		funcs: collection.NewSet[*Func](),
+		fset:        fset,
+		prefix:      prefix,
+		pkgs:        collection.NewSet[*Package](),
+		types:       collection.NewSet[*Type](),
+		funcs:       collection.NewSet[*Func](),
+		diagnostics: diagnostic.NewDiagnostics(),
 	}
 }
 
@@ -35,18 +44,61 @@ func (r *result) Packages() []*Package { return r.pkgs.Items() }
 func (r *result) Types() []*Type       { return r.types.Items() }
 func (r *result) Funcs() Funcs         { return r.funcs.Items() }
 
-// Register the given function declaration.
-func (r *result) RegisterFunc(at *FileResult, decl *ast.FuncDecl) {
-	fn := newFunc(at, decl)
+func (r *result) Diagnostics() *diagnostic.Diagnostics { return r.diagnostics }
 
-	r.funcs.Set(fn.String(), fn)
+// Reports syntax errors of directives found on parsed declarations.
+func (r *result) reportDirectivesErrors() {
+	for _, pkg := range r.pkgs.Items() {
+		r.diagnostics.Add(pkg.directivesErrors()...)
+	}
+
+	for _, typ := range r.types.Items() {
+		r.diagnostics.Add(typ.directivesErrors()...)
+	}
+
+	for _, fn := range r.funcs.Items() {
+		r.diagnostics.Add(fn.directivesErrors()...)
+	}
 }
 
-// Register the given type declaration.
-func (r *result) RegisterType(at *FileResult, decl *ast.TypeSpec, comment *ast.CommentGroup) {
-	typ := newTypeFromDeclaration(at, decl, comment)
+// Validates directives of parsed declarations against the given schemas and report
+// warnings for unknown directives and params. Nothing is reported if no schema is given.
+func (r *result) validateDirectives(schemas []*DirectiveSchema) {
+	if len(schemas) == 0 {
+		return
+	}
+
+	known := make(map[string]*DirectiveSchema, len(schemas))
+
+	for _, schema := range schemas {
+		known[schema.Name] = schema
+	}
+
+	validate := func(decl *Decl, kind DeclKind) {
+		for _, directive := range decl.AllDirectives() {
+			schema, found := known[directive.Name]
+
+			if !found {
+				r.diagnostics.Add(diagnostic.Warnf(directive.Position, codeUnknownDirective,
+					"unknown directive %s", directive.Name))
+				continue
+			}
+
+			r.diagnostics.Add(schema.validate(directive, kind)...)
+		}
+	}
+
+	for _, pkg := range r.pkgs.Items() {
+		validate(pkg.Decl, DeclKindPackage)
+	}
+
+	for _, typ := range r.types.Items() {
+		validate(typ.Decl, DeclKindType)
+	}
 
-	r.types.Set(typ.String(), typ)
+	for _, fn := range r.funcs.Items() {
+		validate(fn.Decl, DeclKindFunc)
+	}
 }
 
 // Package returns the package with the given path if it exists or creates
@@ -55,7 +107,7 @@ func (r *result) Package(path string) *Package {
 	sanitizedPath := strings.Trim(path, "\"")
 
 	return r.pkgs.SetFunc(sanitizedPath, func() *Package {
-		return newPackage(sanitizedPath)
+		return newPackage(r.fset, r.prefix, sanitizedPath)
 	})
 }
 
@@ -82,24 +134,49 @@ type (
 		parent  *result
 		pkg     *Package
 		imports ImportsMap
+		types   []*Type // Declared types waiting to be registered
+		funcs   []*Func // Declared funcs waiting to be registered
 	}
 )
 
-// Build a FileResult scoped to the given package/ast file.
-func (r *result) ParseFile(pkgPath string, file *ast.File) error {
+// Build a FileResult scoped to the given package/ast file. Declarations are only added to
+// the result when calling Register so files of different packages may be parsed concurrently
+// and still be registered in a deterministic order.
+func (r *result) ParseFile(pkgPath string, file *ast.File) (*FileResult, error) {
 	fileResult := &FileResult{
 		parent:  r,
 		pkg:     r.Package(pkgPath),
 		imports: r.ImportsMap(file.Imports),
 	}
 
+	// Keep the first package clause found as the package position
+	if !fileResult.pkg.pos.IsValid() {
+		fileResult.pkg.pos = file.Package
+	}
+
+	// Package documentation may be spread across multiple files
+	if file.Doc != nil {
+		fileResult.pkg.comments = append(fileResult.pkg.comments, file.Doc)
+	}
+
 	for _, decl := range file.Decls {
 		if err := fileResult.visitDeclaration(decl); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return nil
+	return fileResult, nil
+}
+
+// Register declarations found in the file.
+func (r *FileResult) Register() {
+	for _, typ := range r.types {
+		r.parent.types.Set(typ.String(), typ)
+	}
+
+	for _, fn := range r.funcs {
+		r.parent.funcs.Set(fn.String(), fn)
+	}
 }
 
 // Builds a new mapping between package name/alias and package path from a raw ImportSpec array.
@@ -111,7 +188,7 @@ func (r *result) ImportsMap(imports []*ast.ImportSpec) ImportsMap {
 		if i.Name != nil {
 			im[i.Name.Name] = pkg
 		} else {
-			im[pkg.name] = pkg
+			im[pkg.Name()] = pkg
 		}
 	}
 
@@ -125,66 +202,42 @@ func (r *FileResult) visitDeclaration(decl ast.Decl) error {
 			switch s := spec.(type) {
 			case *ast.TypeSpec:
 				// Only handle types declarations for now
-				r.parent.RegisterType(r, s, d.Doc)
+				r.types = append(r.types, newTypeFromDeclaration(r, s, d.Doc))
 			}
 		}
 	case *ast.FuncDecl:
-		r.parent.RegisterFunc(r, d)
+		r.funcs = append(r.funcs, newFunc(r, d))
 	}
 
 	return nil
 }
 
-// Parse a single field and returns a Var. It is defined on a scoped FileResult
-// object because the import mapping is required to correctly resolve a type.
-func (r *FileResult) parseField(field *ast.Field) *Var {
-	var name *ast.Ident
-
-	if len(field.Names) > 0 {
-		name = field.Names[0]
-	}
+// Parse a single field and returns one Var per name (from, to uint gives two vars) or a
+// single unnamed one. It is defined on a scoped FileResult object because the import
+// mapping is required to correctly resolve a type. Type parameters in scope are needed to
+// distinguish them from named types.
+func (r *FileResult) parseField(field *ast.Field, typeParams typeParams) Vars {
+	names := field.Names
 
-	v := &Var{
-		Decl: newDeclaration(name, field.Doc, field.Comment),
+	if len(names) == 0 {
+		names = []*ast.Ident{nil}
 	}
 
-	v.underlying, v.kind = r.parseType(field.Type, r.pkg, VarKindUnknown)
-
-	return v
-}
+	vars := make(Vars, len(names))
 
-func (r *FileResult) parseType(expr ast.Expr, pkg *Package, kind VarKind) (*Type, VarKind) {
-	switch t := expr.(type) {
-	case *ast.Ident:
-		var (
-			typ      = r.parent.Type(pkg, t)
-			nextKind = kind
-		)
+	for i, name := range names {
+		pos := field.Pos()
 
-		if !typ.IsBuiltin() {
-			nextKind |= VarKindIdent
-		} else {
-			nextKind |= VarKindBuiltin
+		if name != nil {
+			pos = name.Pos()
 		}
 
-		return typ, nextKind
-	case *ast.StarExpr:
-		nextKind := kind
-
-		if flag.IsSet(kind, VarKindSlice) {
-			nextKind |= VarKindSliceOfPointer
-		} else {
-			nextKind |= VarKindPointer
+		vars[i] = &Var{
+			Decl:   newDeclaration(r.parent.fset, r.parent.prefix, pos, name, field.Doc, field.Comment),
+			expr:   r.parseExpr(field.Type, r.pkg, typeParams),
+			source: types.ExprString(field.Type),
 		}
-
-		return r.parseType(t.X, pkg, nextKind)
-	case *ast.ArrayType:
-		return r.parseType(t.Elt, pkg, kind|VarKindSlice)
-	case *ast.MapType:
-		return r.parseType(t.Value, pkg, kind|VarKindMap) // FIXME: handle type of key too maybe
-	case *ast.SelectorExpr:
-		return r.parseType(t.Sel, r.imports[t.X.(*ast.Ident).Name], kind)
 	}
 
-	return nil, VarKindUnknown
+	return vars
 }
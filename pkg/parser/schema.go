@@ -0,0 +1,158 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/YuukanOO/ease/pkg/diagnostic"
+	"github.com/YuukanOO/ease/pkg/flag"
+)
+
+const (
+	DeclKindPackage DeclKind = 1 << iota
+	DeclKindType
+	DeclKindFunc
+
+	DeclKindAny = DeclKindPackage | DeclKindType | DeclKindFunc
+)
+
+const (
+	ValueTypeString ValueType = iota // Single value
+	ValueTypeList                    // Comma separated list of values, the key may be repeated
+	ValueTypeBool                    // Boolean, may be given as a bare flag
+	ValueTypeInt                     // Integer
+)
+
+const (
+	codeUnknownDirective       = "unknown-directive"
+	codeUnknownDirectiveParam  = "unknown-directive-param"
+	codeInvalidDirectiveValue  = "invalid-directive-value"
+	codeInvalidDirectiveTarget = "invalid-directive-target"
+)
+
+type (
+	DeclKind  uint // Kind of declarations a directive can be attached to
+	ValueType uint // Expected type of a directive param value
+
+	// Describes a directive understood by an extension, used to warn about unknown directives
+	// and params and to document what is available.
+	DirectiveSchema struct {
+		Name    string
+		Doc     string
+		Targets DeclKind
+		Params  []*ParamSchema
+		Args    string // Description of positional arguments accepted, empty if none
+	}
+
+	// Describes a single directive param.
+	ParamSchema struct {
+		Name string
+		Type ValueType
+		Doc  string
+	}
+
+	// Extensions may implement this interface to declare directives they understand.
+	DirectivesProvider interface {
+		Directives() []*DirectiveSchema
+	}
+)
+
+func (k DeclKind) String() string {
+	var kinds []string
+
+	if flag.IsSet(k, DeclKindPackage) {
+		kinds = append(kinds, "package")
+	}
+
+	if flag.IsSet(k, DeclKindType) {
+		kinds = append(kinds, "type")
+	}
+
+	if flag.IsSet(k, DeclKindFunc) {
+		kinds = append(kinds, "func")
+	}
+
+	return strings.Join(kinds, ", ")
+}
+
+func (t ValueType) String() string {
+	switch t {
+	case ValueTypeList:
+		return "list"
+	case ValueTypeBool:
+		return "bool"
+	case ValueTypeInt:
+		return "int"
+	default:
+		return "string"
+	}
+}
+
+// Retrieve the param with the given name if it exists.
+func (s *DirectiveSchema) Param(name string) (*ParamSchema, bool) {
+	for _, p := range s.Params {
+		if p.Name == name {
+			return p, true
+		}
+	}
+
+	return nil, false
+}
+
+// Validates the given directive attached to a declaration of the given kind against
+// this schema and returns warnings for every problem found.
+func (s *DirectiveSchema) validate(d *Directive, kind DeclKind) []*diagnostic.Diagnostic {
+	var diagnostics []*diagnostic.Diagnostic
+
+	if !flag.IsSet(s.Targets, kind) {
+		diagnostics = append(diagnostics, diagnostic.Warnf(d.Position, codeInvalidDirectiveTarget,
+			"directive %s can not be used on a %s, expected %s", d.Name, kind, s.Targets))
+	}
+
+	for _, key := range d.Keys() {
+		param, found := s.Param(key)
+
+		if !found {
+			diagnostics = append(diagnostics, diagnostic.Warnf(d.Position, codeUnknownDirectiveParam,
+				"unknown param %s for directive %s", key, d.Name))
+			continue
+		}
+
+		if err := param.validate(d.List(key)); err != "" {
+			diagnostics = append(diagnostics, diagnostic.Warnf(d.Position, codeInvalidDirectiveValue,
+				"param %s of directive %s %s", key, d.Name, err))
+		}
+	}
+
+	// Positional arguments are only allowed if expected or if they are boolean flags
+	for _, arg := range d.Args {
+		if param, found := s.Param(arg); s.Args == "" && (!found || param.Type != ValueTypeBool) {
+			diagnostics = append(diagnostics, diagnostic.Warnf(d.Position, codeUnknownDirectiveParam,
+				"unexpected argument %s for directive %s", arg, d.Name))
+		}
+	}
+
+	return diagnostics
+}
+
+// Validates values of a param and returns a description of the problem if any.
+func (p *ParamSchema) validate(values []string) string {
+	if p.Type != ValueTypeList && len(values) > 1 {
+		return "expects a single value"
+	}
+
+	for _, v := range values {
+		switch p.Type {
+		case ValueTypeBool:
+			if _, err := strconv.ParseBool(v); err != nil {
+				return "expects a boolean, got " + v
+			}
+		case ValueTypeInt:
+			if _, err := strconv.Atoi(v); err != nil {
+				return "expects an integer, got " + v
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,4 @@
+package broken
+
+// Returns a string where an int is expected.
+func Broken() int { return "broken" }
@@ -0,0 +1,7 @@
+package testdata
+
+// Function used to check directives validation.
+//
+// ease:known count=1 unknown=2
+// ease:unknown
+func Annotated() {}
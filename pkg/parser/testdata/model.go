@@ -4,3 +4,13 @@ type TestModel struct {
 	ID   int
 	Name string
 }
+
+type TestTaggedModel struct {
+	*TestModel
+	ID       int    `json:"id,omitempty" db:"pk"`
+	Title    string `json:",omitempty"`
+	Secret   string `json:"-"`
+	Dash     string `json:"-,"`
+	Lat, Lng float64
+	internal bool
+}
@@ -0,0 +1,6 @@
+// Package whose name differs from the last element of its path, as does the one it imports.
+package actual
+
+import "gopkg.in/yaml.v3"
+
+func Decode(node *yaml.Node) error { return nil }
@@ -0,0 +1,13 @@
+package testdata
+
+import "context"
+
+// Function used to check how params are parsed.
+func Move(ctx context.Context, from, to uint, opts ...string) {}
+
+// Function used to check how complex type expressions are parsed.
+func Shapes(fn func(int) error, ch <-chan string, arr [4]byte, anon struct{ Name string }) {}
+
+// Function used to check how nested composite types are parsed.
+func Nested(index map[string][]*TestModel, matrix [][2]int, ref **TestModel, events chan<- map[int]string) {
+}
@@ -47,3 +47,42 @@ func NewOtherService(logger Logger) *OtherService {
 func OpenDatabase(DBOptions) Database {
 	return nil
 }
+
+type (
+	Repository[T any] struct {
+		db Database
+	}
+
+	Cache[K comparable, V any] struct {
+		items map[K]V
+	}
+
+	UserService struct {
+		users *Repository[Service]
+		cache *Cache[string, *OtherService]
+	}
+)
+
+func NewRepository[T any](db Database) *Repository[T] {
+	return &Repository[T]{db: db}
+}
+
+func NewCache[K comparable, V any]() *Cache[K, V] {
+	return &Cache[K, V]{items: make(map[K]V)}
+}
+
+func NewUserService(users *Repository[Service], cache *Cache[string, *OtherService]) *UserService {
+	return &UserService{users: users, cache: cache}
+}
+
+type (
+	Mailer interface{}
+
+	Notifier struct {
+		mailer Mailer
+	}
+)
+
+func NewNotifier(mailer Mailer) *Notifier {
+	return &Notifier{mailer: mailer}
+}
@@ -3,6 +3,9 @@ package parser
 import (
 	"fmt"
 	"go/ast"
+	"go/token"
+	"strconv"
+	"sync"
 )
 
 const (
@@ -10,26 +13,43 @@ const (
 	ErrorTypeName   = "error"
 )
 
-type Type struct {
-	*Decl
-	file *FileResult
-	pkg  *Package
-	decl *ast.TypeSpec
-}
+type (
+	Fields []*Field
+
+	Type struct {
+		*Decl
+		lazy       sync.Once
+		file       *FileResult
+		pkg        *Package
+		decl       *ast.TypeSpec
+		cached     *cachedType // Set when restored from the cache instead of an ast declaration
+		typeParams typeParams
+		fields     Fields
+	}
+
+	// Field of a struct type declaration. Embedded fields are named after their type as the
+	// Go specification states.
+	Field struct {
+		*Var
+		tag      string
+		embedded bool
+	}
+)
 
 func newType(pkg *Package, ident *ast.Ident) *Type {
 	return &Type{
-		Decl: newDeclaration(ident),
+		Decl: newDeclaration(nil, "", token.NoPos, ident),
 		pkg:  pkg,
 	}
 }
 
 func newTypeFromDeclaration(at *FileResult, decl *ast.TypeSpec, comment *ast.CommentGroup) *Type {
 	return &Type{
-		Decl: newDeclaration(decl.Name, decl.Doc, comment),
-		file: at,
-		pkg:  at.pkg,
-		decl: decl,
+		Decl:       newDeclaration(at.parent.fset, at.parent.prefix, decl.Pos(), decl.Name, decl.Doc, comment),
+		file:       at,
+		pkg:        at.pkg,
+		decl:       decl,
+		typeParams: newTypeParams(decl.TypeParams),
 	}
 }
 
@@ -39,6 +59,71 @@ func (t *Type) IsBuiltin() bool   { return t.pkg == nil }
 func (t *Type) Package() *Package { return t.pkg }
 func (t *Type) String() string    { return fullyQualifiedName(t.pkg, t.name) }
 
+// Checks if the type is declared in a parsed package, as opposed to builtin types and types
+// only referenced from another package.
+func (t *Type) IsDeclared() bool {
+	if t.decl != nil {
+		return true
+	}
+
+	// Restored from the cache
+	position := t.Position()
+	return position.IsValid()
+}
+
+// Returns names of the type parameters of a generic type declaration.
+func (t *Type) TypeParams() []string { return t.typeParams }
+
+// Returns fields of a struct type declaration, grouped names (X, Y int) are expanded. Other
+// types, and types not declared in a parsed package, have none.
+func (t *Type) Fields() Fields {
+	t.parse()
+	return t.fields
+}
+
+func (t *Type) parse() {
+	t.lazy.Do(func() {
+		if t.cached != nil {
+			t.restore()
+			return
+		}
+
+		if t.decl == nil {
+			return
+		}
+
+		structType, isStruct := t.decl.Type.(*ast.StructType)
+
+		if !isStruct {
+			return
+		}
+
+		for _, field := range structType.Fields.List {
+			var tag string
+
+			if field.Tag != nil {
+				tag, _ = strconv.Unquote(field.Tag.Value)
+			}
+
+			for _, v := range t.file.parseField(field, t.typeParams) {
+				embedded := len(field.Names) == 0
+
+				if embedded && v.Type() != nil {
+					v.name = v.Type().Name()
+				}
+
+				t.fields = append(t.fields, &Field{Var: v, tag: tag, embedded: embedded})
+			}
+		}
+	})
+}
+
+// Tag returns the raw tag of the field without its surrounding quotes, such as
+// json:"id,omitempty" db:"pk".
+func (f *Field) Tag() string { return f.tag }
+
+func (f *Field) IsEmbedded() bool { return f.embedded }
+
 func fullyQualifiedName(pkg *Package, name string) string {
 	if pkg == nil {
 		return name
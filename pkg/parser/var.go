@@ -13,13 +13,39 @@ const (
 	VarKindSlice
 	VarKindSliceOfPointer
 	VarKindMap
+	VarKindVariadic  // Last param declared as ...T, also flagged as a slice
+	VarKindArray     // Array with a fixed length such as [4]T
+	VarKindChan      // Channel of any direction
+	VarKindFunc      // Function type, the underlying type is nil
+	VarKindStruct    // Anonymous struct, the underlying type is nil
+	VarKindInterface // Anonymous interface, the underlying type is nil
+	VarKindTypeParam // Type parameter of a generic declaration, the underlying type is nil
 )
 
+// Kinds of variables which can not be represented by a named type and are mostly
+// unsupported by generators.
+const VarKindUnsupported = VarKindChan | VarKindFunc | VarKindStruct | VarKindInterface | VarKindTypeParam
+
 type Var struct {
 	*Decl
-	kind       VarKind
-	underlying *Type
+	expr   *TypeExpr
+	source string // Type expression as written in the source
+}
+
+// Type returns the innermost named type of the var, Todo for map[string][]*Todo.
+func (v *Var) Type() *Type         { return v.expr.Underlying() }
+func (v *Var) Kind() VarKind       { return v.expr.varKind() }
+func (v *Var) TypeExpr() *TypeExpr { return v.expr }
+func (v *Var) IsPointer() bool     { return flag.IsSet(v.Kind(), VarKindPointer) }
+func (v *Var) IsSlice() bool       { return flag.IsSet(v.Kind(), VarKindSlice) }
+func (v *Var) IsVariadic() bool    { return flag.IsSet(v.Kind(), VarKindVariadic) }
+
+// Checks if the var type could not be represented by a named type.
+func (v *Var) IsUnsupported() bool {
+	kind := v.Kind()
+	return kind == VarKindUnknown || flag.IsSet(kind, VarKindUnsupported)
 }
 
-func (v *Var) Type() *Type     { return v.underlying }
-func (v *Var) IsPointer() bool { return flag.IsSet(v.kind, VarKindPointer) }
+// Expr returns the type expression as written in the source, which may use package aliases
+// local to the file the var is declared in.
+func (v *Var) Expr() string { return v.source }